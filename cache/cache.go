@@ -0,0 +1,224 @@
+// Package cache keeps API responses on disk, and wraps forecast providers,
+// geocoders and ip locators so they answer from it when they can.
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jptoto/weather/forecast"
+	"github.com/jptoto/weather/geocode"
+)
+
+// Cache stores API responses on disk so that running the tool from a shell
+// prompt doesn't burn through API quotas. Entries live in one json file each
+// under Dir/<kind>/ and expire after the TTL for their kind.
+//
+// A nil *Cache is valid and caches nothing.
+type Cache struct {
+	Dir string
+	// Refresh skips reads but still writes, so the next run is fresh.
+	Refresh bool
+	TTLs    map[string]time.Duration
+}
+
+// DefaultTTLs are how long each kind of response is considered fresh.
+var DefaultTTLs = map[string]time.Duration{
+	"forecast": 10 * time.Minute,
+	"geocode":  30 * 24 * time.Hour,
+	"ip":       time.Hour,
+}
+
+type cacheEntry struct {
+	Key    string          `json:"key"`
+	Stored time.Time       `json:"stored"`
+	Value  json.RawMessage `json:"value"`
+}
+
+// New returns a cache in dir, or weather under the user's cache directory when
+// dir is empty. Kinds missing from ttls are never fresh; nil ttls means
+// DefaultTTLs.
+func New(dir string, ttls map[string]time.Duration) (*Cache, error) {
+	if dir == "" {
+		cacheHome, err := os.UserCacheDir()
+		if err != nil {
+			return nil, fmt.Errorf("finding the cache directory failed: %s", err.Error())
+		}
+		dir = filepath.Join(cacheHome, "weather")
+	}
+
+	if ttls == nil {
+		ttls = DefaultTTLs
+	}
+
+	return &Cache{Dir: dir, TTLs: ttls}, nil
+}
+
+func (c *Cache) path(kind, key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.Dir, kind, hex.EncodeToString(sum[:16])+".json")
+}
+
+// Get decodes the cached value for key into v and reports whether a fresh entry
+// was found.
+func (c *Cache) Get(kind, key string, v interface{}) bool {
+	if c == nil || c.Refresh {
+		return false
+	}
+
+	b, err := os.ReadFile(c.path(kind, key))
+	if err != nil {
+		return false
+	}
+
+	var entry cacheEntry
+	if err := json.Unmarshal(b, &entry); err != nil || entry.Key != key {
+		return false
+	}
+
+	if time.Since(entry.Stored) > c.TTLs[kind] {
+		return false
+	}
+
+	return json.Unmarshal(entry.Value, v) == nil
+}
+
+// Set stores v under key. Failing to write the cache is never fatal, so errors
+// are only returned for callers that care.
+func (c *Cache) Set(kind, key string, v interface{}) error {
+	if c == nil {
+		return nil
+	}
+
+	value, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(cacheEntry{Key: key, Stored: time.Now(), Value: value})
+	if err != nil {
+		return err
+	}
+
+	path := c.path(kind, key)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	// Write then rename so a concurrent run never reads half a file.
+	tmp := path + ".tmp" + strconv.Itoa(os.Getpid())
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+// CachedProvider serves forecasts from the cache when it can. Coordinates are
+// rounded to two decimals (about a kilometer) so nearby lookups share entries.
+type CachedProvider struct {
+	Name     string
+	Provider forecast.Provider
+	Cache    *Cache
+}
+
+func (c *CachedProvider) Fetch(ctx context.Context, lat, lon string, opts forecast.Options) (f forecast.Forecast, err error) {
+	key := strings.Join([]string{c.Name, roundCoordinate(lat), roundCoordinate(lon), opts.Units, strings.Join(opts.Exclude, ",")}, "|")
+	if c.Cache.Get("forecast", key, &f) {
+		return f, nil
+	}
+
+	f, err = c.Provider.Fetch(ctx, lat, lon, opts)
+	if err != nil {
+		return f, err
+	}
+	c.Cache.Set("forecast", key, f)
+
+	return f, nil
+}
+
+func roundCoordinate(coordinate string) string {
+	f, err := strconv.ParseFloat(strings.TrimSpace(coordinate), 64)
+	if err != nil {
+		return coordinate
+	}
+
+	return strconv.FormatFloat(math.Round(f*100)/100, 'f', 2, 64)
+}
+
+// CachedGeocoder serves search results from the cache, keyed by the geocoder
+// name and the query string.
+type CachedGeocoder struct {
+	Name     string
+	Geocoder geocode.Geocoder
+	Cache    *Cache
+}
+
+func (c *CachedGeocoder) Search(ctx context.Context, query string) (locations geocode.GeoLocations, err error) {
+	key := c.Name + "|" + strings.ToLower(strings.TrimSpace(query))
+	if c.Cache.Get("geocode", key, &locations) {
+		return locations, nil
+	}
+
+	locations, err = c.Geocoder.Search(ctx, query)
+	if err != nil {
+		return locations, err
+	}
+
+	// Don't remember misses, the user probably made a typo.
+	if len(locations) > 0 {
+		c.Cache.Set("geocode", key, locations)
+	}
+
+	return locations, nil
+}
+
+func (c *CachedGeocoder) Reverse(ctx context.Context, lat, lon string) (geolocation geocode.GeoLocation, err error) {
+	reverse, ok := c.Geocoder.(geocode.ReverseGeocoder)
+	if !ok {
+		return geolocation, fmt.Errorf("geocoder %s doesn't support reverse lookups", c.Name)
+	}
+
+	key := c.Name + "|reverse|" + roundCoordinate(lat) + "|" + roundCoordinate(lon)
+	if c.Cache.Get("geocode", key, &geolocation) {
+		return geolocation, nil
+	}
+
+	geolocation, err = reverse.Reverse(ctx, lat, lon)
+	if err != nil {
+		return geolocation, err
+	}
+	c.Cache.Set("geocode", key, geolocation)
+
+	return geolocation, nil
+}
+
+// CachedIpLocator remembers where the user is for the "ip" TTL.
+type CachedIpLocator struct {
+	Name      string
+	IpLocator geocode.IpLocator
+	Cache     *Cache
+}
+
+func (c *CachedIpLocator) Locate(ctx context.Context) (query string, err error) {
+	if c.Cache.Get("ip", c.Name, &query) {
+		return query, nil
+	}
+
+	query, err = c.IpLocator.Locate(ctx)
+	if err != nil {
+		return query, err
+	}
+	c.Cache.Set("ip", c.Name, query)
+
+	return query, nil
+}
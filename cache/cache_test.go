@@ -0,0 +1,186 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jptoto/weather/forecast"
+	"github.com/jptoto/weather/geocode"
+)
+
+// countingProvider counts the fetches that got past the cache.
+type countingProvider struct {
+	calls int
+}
+
+func (p *countingProvider) Fetch(ctx context.Context, lat, lon string, opts forecast.Options) (forecast.Forecast, error) {
+	p.calls++
+	return forecast.Forecast{Currently: forecast.CurrentWeather{Temperature: float64(p.calls)}}, nil
+}
+
+// countingGeocoder counts the searches that got past the cache.
+type countingGeocoder struct {
+	calls     int
+	locations geocode.GeoLocations
+}
+
+func (g *countingGeocoder) Search(ctx context.Context, query string) (geocode.GeoLocations, error) {
+	g.calls++
+	return g.locations, nil
+}
+
+func TestGetSet(t *testing.T) {
+	tests := []struct {
+		name    string
+		ttls    map[string]time.Duration
+		refresh bool
+		want    bool
+	}{
+		{"fresh", map[string]time.Duration{"forecast": time.Hour}, false, true},
+		{"expired", map[string]time.Duration{"forecast": 0}, false, false},
+		{"no ttl for the kind", map[string]time.Duration{"geocode": time.Hour}, false, false},
+		{"refresh", map[string]time.Duration{"forecast": time.Hour}, true, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c, err := New(t.TempDir(), test.ttls)
+			if err != nil {
+				t.Fatal(err)
+			}
+			c.Refresh = test.refresh
+
+			if err := c.Set("forecast", "key", "value"); err != nil {
+				t.Fatal(err)
+			}
+
+			var got string
+			if ok := c.Get("forecast", "key", &got); ok != test.want {
+				t.Errorf("Get found an entry: %v, want %v", ok, test.want)
+			}
+			if test.want && got != "value" {
+				t.Errorf("got %q, want %q", got, "value")
+			}
+		})
+	}
+}
+
+func TestRefreshStillWrites(t *testing.T) {
+	dir := t.TempDir()
+	refreshing := &Cache{Dir: dir, Refresh: true, TTLs: DefaultTTLs}
+	if err := refreshing.Set("ip", "key", "new"); err != nil {
+		t.Fatal(err)
+	}
+
+	c, _ := New(dir, nil)
+	var got string
+	if !c.Get("ip", "key", &got) || got != "new" {
+		t.Errorf("got %q, want the value written while refreshing", got)
+	}
+}
+
+func TestNilCache(t *testing.T) {
+	var c *Cache
+
+	if err := c.Set("forecast", "key", "value"); err != nil {
+		t.Errorf("Set on a nil cache: %s", err)
+	}
+	var got string
+	if c.Get("forecast", "key", &got) {
+		t.Error("a nil cache found an entry")
+	}
+
+	provider := &countingProvider{}
+	cached := &CachedProvider{Name: "test", Provider: provider}
+	for i := 0; i < 2; i++ {
+		if _, err := cached.Fetch(context.Background(), "40.04", "-75.45", forecast.Options{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if provider.calls != 2 {
+		t.Errorf("provider was called %d times, want every fetch to get through", provider.calls)
+	}
+}
+
+func TestCachedProviderRoundsCoordinates(t *testing.T) {
+	tests := []struct {
+		lat, lon string
+		opts     forecast.Options
+		wantHit  bool
+	}{
+		{"40.0389", "-75.4483", forecast.Options{Units: "us"}, true},
+		{"40.04", "-75.45", forecast.Options{Units: "us"}, true},
+		{" 40.041 ", "-75.449", forecast.Options{Units: "us"}, true},
+		{"40.05", "-75.45", forecast.Options{Units: "us"}, false},
+		{"40.04", "-75.45", forecast.Options{Units: "si"}, false},
+		{"40.04", "-75.45", forecast.Options{Units: "us", Exclude: []string{"minutely"}}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.lat+","+test.lon, func(t *testing.T) {
+			c, _ := New(t.TempDir(), nil)
+			provider := &countingProvider{}
+			cached := &CachedProvider{Name: "test", Provider: provider, Cache: c}
+
+			if _, err := cached.Fetch(context.Background(), "40.0412", "-75.4467", forecast.Options{Units: "us"}); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := cached.Fetch(context.Background(), test.lat, test.lon, test.opts); err != nil {
+				t.Fatal(err)
+			}
+
+			if hit := provider.calls == 1; hit != test.wantHit {
+				t.Errorf("cache hit: %v, want %v", hit, test.wantHit)
+			}
+		})
+	}
+}
+
+func TestRoundCoordinate(t *testing.T) {
+	tests := []struct {
+		coordinate, want string
+	}{
+		{"40.0389", "40.04"},
+		{"-75.4483", "-75.45"},
+		{"0.005", "0.01"},
+		{"12", "12.00"},
+		{"north", "north"},
+	}
+
+	for _, test := range tests {
+		if got := roundCoordinate(test.coordinate); got != test.want {
+			t.Errorf("roundCoordinate(%q) = %q, want %q", test.coordinate, got, test.want)
+		}
+	}
+}
+
+func TestCachedGeocoderSkipsMisses(t *testing.T) {
+	tests := []struct {
+		name      string
+		locations geocode.GeoLocations
+		wantCalls int
+	}{
+		{"found", geocode.GeoLocations{{DisplayName: "Berwyn"}}, 1},
+		{"not found", nil, 2},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c, _ := New(t.TempDir(), nil)
+			geocoder := &countingGeocoder{locations: test.locations}
+			cached := &CachedGeocoder{Name: "test", Geocoder: geocoder, Cache: c}
+
+			// Queries differing only in case and spaces share an entry.
+			for _, query := range []string{"Berwyn", " berwyn "} {
+				if _, err := cached.Search(context.Background(), query); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if geocoder.calls != test.wantCalls {
+				t.Errorf("geocoder was called %d times, want %d", geocoder.calls, test.wantCalls)
+			}
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jptoto/weather/forecast"
+	"github.com/jptoto/weather/geocode"
+	"github.com/jptoto/weather/render"
+)
+
+// AlertReport is an alert as emitted by `weather alerts --format json|yaml`.
+type AlertReport struct {
+	Location string          `json:"location"`
+	Status   string          `json:"status,omitempty"`
+	Severity string          `json:"severity"`
+	Alert    forecast.Alerts `json:"alert"`
+	Start    string          `json:"start_local"`
+	End      string          `json:"end_local"`
+}
+
+const alertsUsage = `usage: weather alerts [flags]
+
+Show the active weather alerts for a location.
+
+With --format json or yaml the alerts are written as one list. With --watch
+each new or updated alert is written as it turns up instead: one compact JSON
+object per line, or one YAML document each.
+
+flags:
+`
+
+// runAlerts implements `weather alerts`.
+func runAlerts(cfg Config, args []string) error {
+	flags := flag.NewFlagSet("alerts", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), alertsUsage)
+		flags.PrintDefaults()
+	}
+
+	var minSeverityName string
+	var event string
+	var watch bool
+	var interval time.Duration
+	flags.StringVar(&cfg.Location, "location", cfg.Location, "Location or saved place name to get alerts for")
+	flags.StringVar(&cfg.Location, "l", cfg.Location, "Location or saved place name to get alerts for (shorthand)")
+	flags.StringVar(&minSeverityName, "min-severity", "unknown", "Only show alerts at least this severe: "+strings.Join(forecast.SeverityNames, ", "))
+	flags.StringVar(&event, "event", "", "Only show alerts whose event contains this text, e.g. \"flood\"")
+	flags.BoolVar(&watch, "watch", false, "Keep polling and print alerts as they're issued or updated")
+	flags.DurationVar(&interval, "interval", 5*time.Minute, "How often --watch polls")
+	flags.StringVar(&cfg.Format, "format", cfg.Format, "Output format: text, json or yaml")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	minSeverity, err := forecast.ParseSeverity(minSeverityName)
+	if err != nil {
+		return err
+	}
+	// A format from the config that alerts can't show, e.g. csv or oneline,
+	// falls back to text. Given as a flag it's a mistake.
+	formatFlag := false
+	flags.Visit(func(f *flag.Flag) { formatFlag = formatFlag || f.Name == "format" })
+	if cfg.Format == "csv" || slices.Contains(render.LineFormats, cfg.Format) {
+		if formatFlag {
+			return fmt.Errorf("%s output isn't supported for alerts, use json or yaml", cfg.Format)
+		}
+		cfg.Format = render.DefaultFormat
+	}
+	if err := render.ValidFormat(cfg.Format); err != nil {
+		return err
+	}
+
+	// Polling is pointless if every poll is answered from the cache.
+	if watch && responseCache != nil {
+		responseCache.Refresh = true
+	}
+
+	services, err := newServices(cfg)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	geolocation, err := geocode.Locate(ctx, services.Geocoder, services.IpLocator, cfg.Location)
+	if err != nil {
+		return err
+	}
+
+	data := forecast.Request{
+		Latitude:  geolocation.Latitude,
+		Longitude: geolocation.Longitude,
+		Exclude:   []string{"current", "minutely", "hourly", "daily"},
+	}
+
+	// id -> fingerprint of every alert printed so far
+	seen := map[string]string{}
+	for {
+		f, err := forecast.Get(ctx, services.Provider, data)
+		if ctx.Err() != nil {
+			// interrupted mid request
+			return nil
+		}
+		if err != nil && !watch {
+			return err
+		}
+		if err != nil {
+			// A failed poll shouldn't end the watch, the next one may work.
+			printError(err)
+		} else {
+			loc := f.Location()
+			alerts := forecast.FilterAlerts(f.Alerts, minSeverity, event)
+
+			if !watch && len(alerts) == 0 && cfg.Format == render.DefaultFormat {
+				fmt.Println(colorize("[green]No active alerts for " + geolocation.DisplayName))
+			}
+
+			reports := []AlertReport{}
+			for _, alert := range alerts {
+				status := ""
+				if watch {
+					fingerprint, ok := seen[forecast.AlertId(alert)]
+					switch {
+					case !ok:
+						status = "NEW"
+					case fingerprint != forecast.AlertFingerprint(alert):
+						status = "UPDATED"
+					default:
+						continue
+					}
+					seen[forecast.AlertId(alert)] = forecast.AlertFingerprint(alert)
+				}
+
+				if cfg.Format == render.DefaultFormat {
+					render.WriteAlert(os.Stdout, alert, loc, status)
+					continue
+				}
+
+				reports = append(reports, AlertReport{
+					Location: geolocation.DisplayName,
+					Status:   strings.ToLower(status),
+					Severity: forecast.ClassifyAlert(alert).String(),
+					Alert:    alert,
+					Start:    forecast.TimeIn(alert.Start, loc).Format(time.RFC3339),
+					End:      forecast.TimeIn(alert.End, loc).Format(time.RFC3339),
+				})
+			}
+
+			if cfg.Format != render.DefaultFormat {
+				if err := writeAlertReports(os.Stdout, cfg.Format, reports, watch); err != nil {
+					return err
+				}
+			}
+		}
+
+		if !watch {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(interval):
+		}
+	}
+}
+
+// writeAlertReports writes reports as one json or yaml list. When watching,
+// output is a stream, so each report goes out on its own as a line of json or
+// a yaml document.
+func writeAlertReports(w io.Writer, format string, reports []AlertReport, watch bool) error {
+	if !watch {
+		return render.EncodeStructured(w, format, reports)
+	}
+
+	for _, report := range reports {
+		if format == "json" {
+			if err := json.NewEncoder(w).Encode(report); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if _, err := fmt.Fprintln(w, "---"); err != nil {
+			return err
+		}
+		if err := render.EncodeStructured(w, format, report); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
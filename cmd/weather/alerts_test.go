@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/jptoto/weather/forecast"
+	"github.com/jptoto/weather/internal/fixtures"
+)
+
+func TestWriteAlertReports(t *testing.T) {
+	reports := []AlertReport{
+		{Location: "Berwyn", Severity: "moderate", Alert: forecast.Alerts{Event: "Flood Watch"}},
+		{Location: "Berwyn", Severity: "severe", Alert: forecast.Alerts{Event: "Flood Warning"}},
+	}
+
+	events := func(t *testing.T, format string, b []byte, watch bool) (got []string) {
+		var decoded []AlertReport
+		switch {
+		case format == "json" && !watch:
+			if err := json.Unmarshal(b, &decoded); err != nil {
+				t.Fatalf("%s\n%s", err, b)
+			}
+		case format == "json":
+			for _, line := range strings.Split(strings.TrimSpace(string(b)), "\n") {
+				var report AlertReport
+				if err := json.Unmarshal([]byte(line), &report); err != nil {
+					t.Fatalf("line %q: %s", line, err)
+				}
+				decoded = append(decoded, report)
+			}
+		default:
+			dec := yaml.NewDecoder(bytes.NewReader(b))
+			for {
+				var v interface{}
+				err := dec.Decode(&v)
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				if err != nil {
+					t.Fatalf("%s\n%s", err, b)
+				}
+				// round trip through json to use the json field names
+				j, _ := json.Marshal(v)
+				if watch {
+					var report AlertReport
+					json.Unmarshal(j, &report)
+					decoded = append(decoded, report)
+				} else {
+					json.Unmarshal(j, &decoded)
+				}
+			}
+		}
+
+		for _, report := range decoded {
+			got = append(got, report.Alert.Event)
+		}
+		return got
+	}
+
+	for _, format := range []string{"json", "yaml"} {
+		for _, watch := range []bool{false, true} {
+			var buf bytes.Buffer
+			if err := writeAlertReports(&buf, format, reports, watch); err != nil {
+				t.Fatal(err)
+			}
+
+			got := strings.Join(events(t, format, buf.Bytes(), watch), ", ")
+			if got != "Flood Watch, Flood Warning" {
+				t.Errorf("%s watch=%v: got %q, want both alerts\n%s", format, watch, got, buf.Bytes())
+			}
+		}
+	}
+
+	// No alerts is an empty list, not nothing at all.
+	for format, want := range map[string]string{"json": "[]\n", "yaml": "[]\n"} {
+		var buf bytes.Buffer
+		if err := writeAlertReports(&buf, format, []AlertReport{}, false); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != want {
+			t.Errorf("%s: got %q for no alerts, want %q", format, buf.String(), want)
+		}
+	}
+}
+
+func TestAlertsJson(t *testing.T) {
+	server := fixtures.NewServer(t)
+	cfg := fixtureConfig(t, server)
+	cfg.Location = "Berwyn, PA"
+
+	var err error
+	output := capture(t, func() { err = runAlerts(cfg, []string{"--format", "json"}) })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var reports []AlertReport
+	if err := json.Unmarshal([]byte(output), &reports); err != nil {
+		t.Fatalf("%s\n%s", err, output)
+	}
+	if len(reports) != 1 || reports[0].Alert.Event != "Flood Watch" || reports[0].Severity != "moderate" {
+		t.Errorf("got %+v, want the fixture's flood watch", reports)
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/jptoto/weather"
+	"github.com/jptoto/weather/geocode"
+	"github.com/jptoto/weather/render"
+	"github.com/jptoto/weather/units"
+)
+
+// compareLocations geocodes every query, then fetches every forecast, each step
+// concurrently. All forecasts share one unit system so they can be compared; for
+// "auto" it is picked from the first location that resolved.
+func compareLocations(ctx context.Context, services weather.Services, queries []string, opts geocode.LocateOptions, unitSystem string) (results []render.Comparison, resolvedUnits string, err error) {
+	results = make([]render.Comparison, len(queries))
+
+	var wg sync.WaitGroup
+	for i, query := range queries {
+		results[i].Query = query
+		wg.Add(1)
+		go func(result *render.Comparison) {
+			defer wg.Done()
+			result.Location, result.Err = geocode.LocateWith(ctx, services.Geocoder, services.IpLocator, result.Query, opts)
+		}(&results[i])
+	}
+	wg.Wait()
+
+	resolvedUnits = unitSystem
+	for _, result := range results {
+		if result.Err == nil {
+			resolvedUnits, err = units.Resolve(unitSystem, result.Location.Address.CountryCode)
+			break
+		}
+	}
+	if err != nil {
+		return results, resolvedUnits, err
+	}
+
+	for i := range results {
+		if results[i].Err != nil {
+			continue
+		}
+		wg.Add(1)
+		go func(result *render.Comparison) {
+			defer wg.Done()
+			lookup, err := services.Forecast(ctx, result.Location, weather.LookupOptions{
+				Units:   resolvedUnits,
+				Exclude: []string{"minutely", "hourly"},
+			})
+			if err != nil {
+				result.Err = err
+				return
+			}
+			result.Forecast = lookup.Forecast
+		}(&results[i])
+	}
+	wg.Wait()
+
+	return results, resolvedUnits, nil
+}
+
+// queriesForPlaces expands --places into saved place names. "all" means every
+// saved place.
+func queriesForPlaces(places Places, names string) (queries []string, err error) {
+	if names == "all" {
+		return slices.Sorted(maps.Keys(places)), nil
+	}
+
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if _, ok := places.Get(name); !ok {
+			return nil, fmt.Errorf("no saved place named %q", name)
+		}
+		queries = append(queries, name)
+	}
+
+	return queries, nil
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jptoto/weather/geocode"
+	"github.com/jptoto/weather/internal/fixtures"
+)
+
+func TestCompareLocations(t *testing.T) {
+	server := fixtures.NewServer(t)
+	services, err := newServices(fixtureConfig(t, server))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results, units, err := compareLocations(context.Background(), services, []string{"Nowhere", "Berwyn, PA"}, geocode.LocateOptions{}, "auto")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if units != "us" {
+		t.Errorf("resolved units %q, want us from the location that was found", units)
+	}
+	if results[0].Err == nil {
+		t.Errorf("expected Nowhere to fail")
+	}
+	if results[1].Err != nil || len(results[1].Forecast.Daily) == 0 {
+		t.Errorf("Berwyn, PA: %v", results[1].Err)
+	}
+}
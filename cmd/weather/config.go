@@ -0,0 +1,430 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+
+	"github.com/jptoto/weather/cache"
+	"github.com/jptoto/weather/forecast"
+	"github.com/jptoto/weather/geocode"
+	"github.com/jptoto/weather/render"
+	"github.com/jptoto/weather/units"
+)
+
+// Config holds every setting that can be changed without flags. Values are
+// layered, each overriding the last: the defaults in defaultConfig, the config
+// file, environment variables and finally command line flags.
+//
+// An example ~/.config/weather/config.toml:
+//
+//	location = "Berwyn, PA"
+//	units = "us"
+//	days = 3
+//
+//	[api_keys]
+//	openweathermap = "..."
+//	geocoding = "..."
+//
+//	[cache]
+//	forecast_ttl = "15m"
+//
+//	[notify]
+//	slack = "https://hooks.slack.com/services/..."
+//	rules = ["temp < 0", "gust > 15", "pop > 80"]
+//
+//	[serve]
+//	locations = ["home", "office"]
+//
+//	[oneline]
+//	template = "{icon} {temp}{unit} {wind_dir}"
+//	glyphs = "nerd"
+type Config struct {
+	Location    string         `toml:"location"`
+	Units       string         `toml:"units"`
+	Days        int            `toml:"days"`
+	Provider    string         `toml:"provider"`
+	ProviderUrl string         `toml:"provider_url"`
+	Geocoder    string         `toml:"geocoder"`
+	GeocoderUrl string         `toml:"geocoder_url"`
+	IpLocator   string         `toml:"ip_locator"`
+	IpLookupUrl string         `toml:"ip_lookup_url"`
+	IpGeoUrl    string         `toml:"ip_geo_url"`
+	Gazetteer   string         `toml:"gazetteer"`
+	Format      string         `toml:"format"`
+	Template    string         `toml:"template"`
+	Color       bool           `toml:"color"`
+	ApiKeys     ApiKeys        `toml:"api_keys"`
+	Cache       CacheConfig    `toml:"cache"`
+	Notify      NotifyConfig   `toml:"notify"`
+	Serve       ServeConfig    `toml:"serve"`
+	Exporter    ExporterConfig `toml:"exporter"`
+	Oneline     OnelineConfig  `toml:"oneline"`
+	Http        HttpConfig     `toml:"http"`
+}
+
+type ApiKeys struct {
+	OpenWeatherMap string `toml:"openweathermap"`
+	Geocoding      string `toml:"geocoding"`
+}
+
+type CacheConfig struct {
+	Enabled     bool     `toml:"enabled"`
+	Dir         string   `toml:"dir"`
+	ForecastTTL Duration `toml:"forecast_ttl"`
+	GeocodeTTL  Duration `toml:"geocode_ttl"`
+	IpTTL       Duration `toml:"ip_ttl"`
+}
+
+type NotifyConfig struct {
+	Webhook     string   `toml:"webhook"`
+	Slack       string   `toml:"slack"`
+	Command     string   `toml:"command"`
+	Rules       []string `toml:"rules"`
+	MinSeverity string   `toml:"min_severity"`
+	Lookahead   int      `toml:"lookahead"`
+}
+
+type ServeConfig struct {
+	Addr      string   `toml:"addr"`
+	Interval  Duration `toml:"interval"`
+	Locations []string `toml:"locations"`
+}
+
+type ExporterConfig struct {
+	Addr string `toml:"addr"`
+}
+
+// OnelineConfig is the line printed by --oneline and the waybar and i3bar
+// formats. Template fields are listed in render.LineFields.
+type OnelineConfig struct {
+	Template string `toml:"template"`
+	Glyphs   string `toml:"glyphs"`
+}
+
+// HttpConfig tunes the shared HTTP client. Timeout applies to each attempt,
+// Retries is how many more attempts a failed request gets.
+type HttpConfig struct {
+	Timeout Duration `toml:"timeout"`
+	Retries int      `toml:"retries"`
+}
+
+// Duration lets TOML files use strings like "10m" for time.Duration values.
+type Duration struct {
+	time.Duration
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+func (d *Duration) UnmarshalText(text []byte) (err error) {
+	d.Duration, err = time.ParseDuration(string(text))
+	return err
+}
+
+// ConfigEnv maps environment variables onto config keys. The API key variables
+// predate the config file and are kept for compatibility.
+var ConfigEnv = map[string]string{
+	"OPENWEATHERMAP_API_KEY": "api_keys.openweathermap",
+	"GEOCODING_API_KEY":      "api_keys.geocoding",
+	"WEATHER_LOCATION":       "location",
+	"WEATHER_UNITS":          "units",
+	"WEATHER_DAYS":           "days",
+	"WEATHER_PROVIDER":       "provider",
+	"WEATHER_PROVIDER_URL":   "provider_url",
+	"WEATHER_GEOCODER":       "geocoder",
+	"WEATHER_GEOCODER_URL":   "geocoder_url",
+	"WEATHER_IP_LOOKUP_URL":  "ip_lookup_url",
+	"WEATHER_IP_GEO_URL":     "ip_geo_url",
+	"WEATHER_GAZETTEER":      "gazetteer",
+	"WEATHER_FORMAT":         "format",
+	"WEATHER_TEMPLATE":       "template",
+	"WEATHER_NOTIFY_WEBHOOK": "notify.webhook",
+	"WEATHER_NOTIFY_SLACK":   "notify.slack",
+	"WEATHER_ONELINE":        "oneline.template",
+	"WEATHER_GLYPHS":         "oneline.glyphs",
+}
+
+func defaultConfig() Config {
+	return Config{
+		Units:     units.Default,
+		Provider:  forecast.DefaultProvider,
+		Geocoder:  geocode.DefaultGeocoder,
+		IpLocator: geocode.DefaultIpLocator,
+		Format:    render.DefaultFormat,
+		Color:     true,
+		Cache: CacheConfig{
+			Enabled:     true,
+			ForecastTTL: Duration{cache.DefaultTTLs["forecast"]},
+			GeocodeTTL:  Duration{cache.DefaultTTLs["geocode"]},
+			IpTTL:       Duration{cache.DefaultTTLs["ip"]},
+		},
+		Notify: NotifyConfig{
+			MinSeverity: "unknown",
+			Lookahead:   12,
+		},
+		Serve: ServeConfig{
+			Addr:     "127.0.0.1:8765",
+			Interval: Duration{10 * time.Minute},
+		},
+		Exporter: ExporterConfig{
+			Addr: "127.0.0.1:9776",
+		},
+		Oneline: OnelineConfig{
+			Template: render.DefaultLineTemplate,
+			Glyphs:   render.DefaultGlyphs,
+		},
+		Http: HttpConfig{
+			Timeout: Duration{10 * time.Second},
+			Retries: 2,
+		},
+	}
+}
+
+// configPath is $WEATHER_CONFIG, or weather/config.toml under the user's config
+// directory.
+func configPath() (string, error) {
+	if path := os.Getenv("WEATHER_CONFIG"); path != "" {
+		return path, nil
+	}
+
+	configHome, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("finding the config directory failed: %s", err.Error())
+	}
+
+	return filepath.Join(configHome, "weather", "config.toml"), nil
+}
+
+// loadConfig layers the config file and environment over the defaults. A
+// missing config file is not an error.
+func loadConfig() (cfg Config, err error) {
+	cfg = defaultConfig()
+
+	path, err := configPath()
+	if err != nil {
+		return cfg, err
+	}
+
+	if _, err := toml.DecodeFile(path, &cfg); err != nil && !os.IsNotExist(err) {
+		return cfg, fmt.Errorf("reading config file %s failed: %s", path, err.Error())
+	}
+
+	for _, env := range slices.Sorted(maps.Keys(ConfigEnv)) {
+		if value, ok := os.LookupEnv(env); ok && value != "" {
+			if err := setConfigValue(&cfg, ConfigEnv[env], value); err != nil {
+				return cfg, fmt.Errorf("environment variable %s: %s", env, err.Error())
+			}
+		}
+	}
+
+	// https://no-color.org
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		cfg.Color = false
+	}
+
+	return cfg, nil
+}
+
+// configField finds the struct field for a dotted key like "cache.forecast_ttl".
+func configField(cfg *Config, key string) (reflect.Value, error) {
+	v := reflect.ValueOf(cfg).Elem()
+
+	for _, part := range strings.Split(key, ".") {
+		if v.Kind() != reflect.Struct || v.Type() == reflect.TypeOf(Duration{}) {
+			return v, fmt.Errorf("unknown config key %q", key)
+		}
+
+		found := false
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).Tag.Get("toml") == part {
+				v = v.Field(i)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return v, fmt.Errorf("unknown config key %q", key)
+		}
+	}
+
+	if v.Kind() == reflect.Struct && v.Type() != reflect.TypeOf(Duration{}) {
+		return v, fmt.Errorf("config key %q is a table, set one of its keys instead", key)
+	}
+
+	return v, nil
+}
+
+// parseConfigValue converts the string form of value into whatever type key
+// holds.
+func parseConfigValue(key, value string) (interface{}, error) {
+	var cfg Config
+	field, err := configField(&cfg, key)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case field.Type() == reflect.TypeOf(Duration{}):
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a duration like 10m or 2h", value)
+		}
+		return Duration{d}, nil
+	case field.Kind() == reflect.Int:
+		i, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a number", value)
+		}
+		return i, nil
+	case field.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not true or false", value)
+		}
+		return b, nil
+	case field.Kind() == reflect.Slice:
+		var list []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		return list, nil
+	}
+
+	return value, nil
+}
+
+func setConfigValue(cfg *Config, key, value string) error {
+	parsed, err := parseConfigValue(key, value)
+	if err != nil {
+		return err
+	}
+
+	field, err := configField(cfg, key)
+	if err != nil {
+		return err
+	}
+	field.Set(reflect.ValueOf(parsed))
+
+	return nil
+}
+
+// writeConfigValue sets a single key in the config file, leaving the rest of
+// the file's settings alone.
+func writeConfigValue(path, key, value string) error {
+	parsed, err := parseConfigValue(key, value)
+	if err != nil {
+		return err
+	}
+
+	file := map[string]interface{}{}
+	if _, err := toml.DecodeFile(path, &file); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("reading config file %s failed: %s", path, err.Error())
+	}
+
+	// Walk down to the table holding the key, creating tables as we go.
+	table := file
+	parts := strings.Split(key, ".")
+	for _, part := range parts[:len(parts)-1] {
+		next, ok := table[part].(map[string]interface{})
+		if !ok {
+			next = map[string]interface{}{}
+			table[part] = next
+		}
+		table = next
+	}
+	table[parts[len(parts)-1]] = parsed
+
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(file); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	// The file may hold API keys, keep it private.
+	return os.WriteFile(path, buf.Bytes(), 0o600)
+}
+
+// maskSecret hides all but the last four characters of an API key.
+func maskSecret(secret string) string {
+	if len(secret) <= 4 {
+		return strings.Repeat("*", len(secret))
+	}
+
+	return strings.Repeat("*", len(secret)-4) + secret[len(secret)-4:]
+}
+
+// maskUrl masks everything after the host of a webhook url, where Slack and
+// most webhooks keep their secret.
+func maskUrl(s string) string {
+	u, err := url.Parse(s)
+	if err != nil || u.Host == "" {
+		return maskSecret(s)
+	}
+
+	host := u.Scheme + "://" + u.Host + "/"
+	return host + maskSecret(strings.TrimPrefix(s, host))
+}
+
+const configUsage = `usage: weather config <command>
+
+commands:
+  show               print the effective config, API keys and webhooks masked
+  set <key> <value>  set a key in the config file, e.g. "units us" or "cache.forecast_ttl 15m",
+                     lists are comma separated
+  path               print the config file path
+`
+
+// runConfig implements `weather config show|set|path`.
+func runConfig(args []string) error {
+	if len(args) == 0 || isHelp(args[0]) {
+		fmt.Print(configUsage)
+		return nil
+	}
+
+	path, err := configPath()
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "path":
+		fmt.Println(path)
+	case "show":
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		cfg.ApiKeys.OpenWeatherMap = maskSecret(cfg.ApiKeys.OpenWeatherMap)
+		cfg.ApiKeys.Geocoding = maskSecret(cfg.ApiKeys.Geocoding)
+		cfg.Notify.Webhook = maskUrl(cfg.Notify.Webhook)
+		cfg.Notify.Slack = maskUrl(cfg.Notify.Slack)
+		return toml.NewEncoder(os.Stdout).Encode(cfg)
+	case "set":
+		if len(args) != 3 {
+			return fmt.Errorf("usage: weather config set <key> <value>")
+		}
+		return writeConfigValue(path, args[1], args[2])
+	default:
+		fmt.Print(configUsage)
+		return fmt.Errorf("unknown config command %q", args[0])
+	}
+
+	return nil
+}
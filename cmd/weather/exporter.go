@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/jptoto/weather/forecast"
+	"github.com/jptoto/weather/units"
+)
+
+// A metric is one Prometheus metric family, written in the text exposition
+// format: https://prometheus.io/docs/instrumenting/exposition_formats/
+type metric struct {
+	Name    string
+	Help    string
+	Type    string
+	Samples []metricSample
+}
+
+type metricSample struct {
+	Labels [][2]string
+	Value  float64
+}
+
+func (m *metric) add(value float64, labels ...string) {
+	sample := metricSample{Value: value}
+	for i := 0; i+1 < len(labels); i += 2 {
+		sample.Labels = append(sample.Labels, [2]string{labels[i], labels[i+1]})
+	}
+	m.Samples = append(m.Samples, sample)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func (m *metric) write(w io.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n", m.Name, m.Help)
+	fmt.Fprintf(w, "# TYPE %s %s\n", m.Name, m.Type)
+
+	for _, sample := range m.Samples {
+		labels := make([]string, len(sample.Labels))
+		for i, label := range sample.Labels {
+			labels[i] = fmt.Sprintf(`%s="%s"`, label[0], labelEscaper.Replace(label[1]))
+		}
+
+		fmt.Fprintf(w, "%s{%s} %s\n", m.Name, strings.Join(labels, ","), strconv.FormatFloat(sample.Value, 'g', -1, 64))
+	}
+}
+
+// currentMetrics are the gauges exported for each location's current
+// conditions, in si units apart from visibility which stays in meters.
+var currentMetrics = []struct {
+	Name  string
+	Help  string
+	Value func(forecast.CurrentWeather) float64
+}{
+	{"weather_temperature_celsius", "Current temperature.", func(c forecast.CurrentWeather) float64 { return units.KelvinToCelsius(c.Temperature) }},
+	{"weather_feels_like_celsius", "Current apparent temperature.", func(c forecast.CurrentWeather) float64 { return units.KelvinToCelsius(c.FeelsLike) }},
+	{"weather_humidity_percent", "Current relative humidity.", func(c forecast.CurrentWeather) float64 { return float64(c.Humidity) }},
+	{"weather_pressure_hectopascals", "Current sea level pressure.", func(c forecast.CurrentWeather) float64 { return c.Pressure }},
+	{"weather_dew_point_celsius", "Current dew point.", func(c forecast.CurrentWeather) float64 { return units.KelvinToCelsius(c.DewPoint) }},
+	{"weather_uv_index", "Current UV index.", func(c forecast.CurrentWeather) float64 { return c.Uvi }},
+	{"weather_clouds_percent", "Current cloud cover.", func(c forecast.CurrentWeather) float64 { return float64(c.Clouds) }},
+	{"weather_visibility_meters", "Current visibility.", func(c forecast.CurrentWeather) float64 { return c.Visibility }},
+	{"weather_wind_speed_meters_per_second", "Current wind speed.", func(c forecast.CurrentWeather) float64 { return c.WindSpeed }},
+	{"weather_wind_direction_degrees", "Current direction the wind is blowing from.", func(c forecast.CurrentWeather) float64 { return float64(c.WindDegree) }},
+}
+
+// metrics builds every metric family from the served locations. Locations
+// without a forecast yet only get their up, error and update metrics.
+func (s *Server) metrics() []*metric {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	up := &metric{Name: "weather_up", Help: "Whether the last refresh of the location succeeded.", Type: "gauge"}
+	errors := &metric{Name: "weather_scrape_errors_total", Help: "Refreshes of the location that failed.", Type: "counter"}
+	updated := &metric{Name: "weather_last_update_timestamp_seconds", Help: "When the location's forecast was last refreshed.", Type: "gauge"}
+	alerts := &metric{Name: "weather_alerts_active", Help: "Active weather alerts by severity.", Type: "gauge"}
+
+	current := make([]*metric, len(currentMetrics))
+	for i, m := range currentMetrics {
+		current[i] = &metric{Name: m.Name, Help: m.Help, Type: "gauge"}
+	}
+
+	for _, name := range s.names {
+		served := s.locations[name]
+
+		if served.Err == nil && !served.Updated.IsZero() {
+			up.add(1, "location", name)
+		} else {
+			up.add(0, "location", name)
+		}
+		errors.add(float64(served.Errors), "location", name)
+
+		if served.Updated.IsZero() {
+			continue
+		}
+		updated.add(float64(served.Updated.Unix()), "location", name)
+
+		for i, m := range currentMetrics {
+			current[i].add(m.Value(served.Forecast.Currently), "location", name)
+		}
+
+		// Every severity gets a sample so series don't vanish when an alert
+		// expires.
+		counts := map[forecast.Severity]int{}
+		for _, alert := range served.Forecast.Alerts {
+			counts[forecast.ClassifyAlert(alert)]++
+		}
+		for i, severity := range forecast.SeverityNames {
+			alerts.add(float64(counts[forecast.Severity(i)]), "location", name, "severity", severity)
+		}
+	}
+
+	return append(append([]*metric{up, errors, updated}, current...), alerts)
+}
+
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+	for _, m := range s.metrics() {
+		m.write(w)
+	}
+}
+
+// MetricsHandler serves just /metrics, for `weather exporter`.
+func (s *Server) MetricsHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /metrics", s.handleMetrics)
+
+	return mux
+}
+
+const exporterUsage = `usage: weather exporter [flags]
+
+Serve current conditions and alert counts for a set of locations as Prometheus
+metrics on /metrics. Locations are picked the same way as weather serve.
+
+flags:
+`
+
+// runExporter implements `weather exporter`.
+func runExporter(cfg Config, args []string) error {
+	cfg.Serve.Addr = cfg.Exporter.Addr
+	return runServer(cfg, args, "exporter", exporterUsage, (*Server).MetricsHandler)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jptoto/weather/internal/fixtures"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata/golden")
+
+func TestExporterMetrics(t *testing.T) {
+	silence(t)
+	server := fixtures.NewServer(t)
+	services, err := newServices(fixtureConfig(t, server))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The name needs escaping as a label value, and nowhere never resolves.
+	s := NewServer(services, "auto", map[string]string{"o\"brien\\\nhome": "Berwyn, PA", "nowhere": "Nowhere"})
+	s.Refresh(context.Background())
+	for _, served := range s.locations {
+		if !served.Updated.IsZero() {
+			served.Updated = time.Unix(1745601315, 0)
+		}
+	}
+
+	recorder := httptest.NewRecorder()
+	s.MetricsHandler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	if contentType := recorder.Header().Get("Content-Type"); contentType != "text/plain; version=0.0.4; charset=utf-8" {
+		t.Errorf("got content type %q, want the Prometheus text format", contentType)
+	}
+
+	path := filepath.Join("testdata", "golden", "metrics.txt")
+	got := recorder.Body.Bytes()
+	if *update {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading golden file: %s, run go test -update to create it", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("output doesn't match %s, run go test -update if the change is intended\n--- got\n%s\n--- want\n%s", path, got, want)
+	}
+}
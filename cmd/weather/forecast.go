@@ -0,0 +1,440 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/jptoto/weather"
+	"github.com/jptoto/weather/geocode"
+	"github.com/jptoto/weather/render"
+)
+
+// A forecastCommand is one of the commands that show a forecast: bare
+// `weather`, `weather now`, `weather hourly` and `weather daily`. They share
+// the location, unit, service and output flags, and each adds the flags for
+// the sections it shows.
+type forecastCommand struct {
+	Name  string
+	Usage string
+	// Current shows the current conditions and alerts.
+	Current bool
+	// Nowcast, Hourly and Daily add the --nowcast, --hours and --days flags.
+	Nowcast bool
+	Hourly  bool
+	Daily   bool
+	// Hours and Days are the defaults for --hours and --days. The configured
+	// days win over Days when there are more than one of them.
+	Hours int
+	Days  int
+}
+
+const weatherUsage = `usage: weather [command] [flags]
+
+Show the weather for a location. Without a command, shows the current
+conditions plus whatever --nowcast, --hours and --days ask for.
+
+commands:
+  now       current conditions and alerts
+  hourly    hour by hour forecast
+  daily     day by day forecast
+  alerts    active weather alerts
+  places    save, remove and list named places
+  config    show or change the config file
+  notify    send alerts and threshold crossings to a webhook, Slack or command
+  serve     serve forecasts as JSON over HTTP
+  exporter  serve forecasts as Prometheus metrics
+
+Run "weather <command> -h" for a command's flags.
+
+flags:
+`
+
+var (
+	weatherCommand = forecastCommand{
+		Usage:   weatherUsage,
+		Current: true,
+		Nowcast: true,
+		Hourly:  true,
+		Daily:   true,
+	}
+	nowCommand = forecastCommand{
+		Name: "now",
+		Usage: `usage: weather now [flags]
+
+Show the current conditions and any active alerts. Give more than one
+location to compare them side by side.
+
+flags:
+`,
+		Current: true,
+		Nowcast: true,
+	}
+	hourlyCommand = forecastCommand{
+		Name: "hourly",
+		Usage: `usage: weather hourly [flags]
+
+Show the hour by hour forecast, in the location's time zone.
+
+flags:
+`,
+		Hourly: true,
+		Hours:  12,
+	}
+	dailyCommand = forecastCommand{
+		Name: "daily",
+		Usage: `usage: weather daily [flags]
+
+Show the day by day forecast. Give more than one location to compare their
+highs and lows side by side.
+
+flags:
+`,
+		Daily: true,
+		Days:  7,
+	}
+)
+
+func runNow(cfg Config, args []string) error    { return runForecast(cfg, nowCommand, args) }
+func runHourly(cfg Config, args []string) error { return runForecast(cfg, hourlyCommand, args) }
+func runDaily(cfg Config, args []string) error  { return runForecast(cfg, dailyCommand, args) }
+
+// runWeather implements bare `weather`, which takes every forecast flag.
+func runWeather(cfg Config, args []string) error {
+	return runForecast(cfg, weatherCommand, args)
+}
+
+func runForecast(cfg Config, command forecastCommand, args []string) error {
+	name := "weather"
+	if command.Name != "" {
+		name += " " + command.Name
+	}
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), command.Usage)
+		flags.PrintDefaults()
+	}
+
+	// Only commands with a daily forecast take the configured days.
+	hours := command.Hours
+	days := 0
+	if command.Daily {
+		days = cfg.Days
+	}
+	if command.Days > 0 && days <= 1 {
+		days = command.Days
+	}
+
+	var verbose bool
+	var nowcast bool
+	var ignoreAlerts bool
+	var version bool
+	var noCache bool
+	var noColor bool
+	var refresh bool
+	var placeNames string
+	var lat string
+	var lon string
+	var pick bool
+	var oneline bool
+	var templateText string
+	var locateOpts geocode.LocateOptions
+
+	// the config file and environment provide the defaults
+	if command.Name == "" {
+		flags.BoolVar(&version, "version", false, "print version and exit")
+		flags.BoolVar(&version, "v", false, "print version and exit (shorthand)")
+	}
+	var queries []string
+	if cfg.Location != "" {
+		queries = []string{cfg.Location}
+	}
+	locations := &stringList{values: &queries}
+	flags.Var(locations, "location", "Location or saved place name to get the weather, repeat to compare locations")
+	flags.Var(locations, "l", "Location or saved place name to get the weather (shorthand)")
+	flags.StringVar(&cfg.Units, "units", cfg.Units, "System of units: auto, us, si, ca or uk")
+	flags.StringVar(&cfg.Units, "u", cfg.Units, "System of units (shorthand)")
+	if command.Daily {
+		flags.IntVar(&days, "days", days, "No. of days to get forecast")
+		flags.IntVar(&days, "d", days, "No. of days to get forecast (shorthand)")
+	}
+	if command.Hourly {
+		flags.IntVar(&hours, "hours", hours, "No. of hours to get forecast")
+		flags.BoolVar(&verbose, "verbose", false, "Show more detail in the hourly forecast")
+	}
+	if command.Nowcast {
+		flags.BoolVar(&nowcast, "nowcast", false, "Show minute by minute precipitation for the next hour")
+	}
+	flags.StringVar(&lat, "lat", "", "Latitude to get the weather for, use with --lon instead of --location")
+	flags.StringVar(&lon, "lon", "", "Longitude to get the weather for, use with --lat instead of --location")
+	flags.BoolVar(&pick, "pick", false, "List the matching locations and choose one")
+	flags.BoolVar(&pick, "interactive", false, "List the matching locations and choose one (alias for --pick)")
+	flags.StringVar(&locateOpts.Country, "country", "", "Only match locations in this country, name or code")
+	flags.StringVar(&locateOpts.State, "state", "", "Only match locations in this state or region, name or code")
+	flags.IntVar(&locateOpts.MatchIndex, "match-index", 0, "Use the nth best matching location instead of the first")
+	flags.StringVar(&placeNames, "places", "", "Comma separated saved places to compare, or \"all\"")
+	flags.BoolVar(&ignoreAlerts, "ignore-alerts", false, "Ignore alerts in weather output")
+	flags.StringVar(&cfg.Provider, "provider", cfg.Provider, "Forecast provider to use")
+	flags.StringVar(&cfg.ProviderUrl, "provider-url", cfg.ProviderUrl, "Base url of the forecast provider, e.g. a proxy or test server")
+	flags.StringVar(&cfg.Geocoder, "geocoder", cfg.Geocoder, "Geocoding service to use")
+	flags.StringVar(&cfg.GeocoderUrl, "geocoder-url", cfg.GeocoderUrl, "Base url of the geocoding service, e.g. a Nominatim mirror")
+	flags.StringVar(&cfg.Gazetteer, "gazetteer", cfg.Gazetteer, "Directory holding GeoNames cities.txt/zip.txt for offline geocoding")
+	flags.StringVar(&cfg.IpLocator, "ip-locator", cfg.IpLocator, "Service used to find your location when none is given")
+	if command.Current {
+		flags.StringVar(&cfg.Format, "format", cfg.Format, "Output format: text, json, yaml, csv, oneline, waybar or i3bar")
+		flags.BoolVar(&oneline, "oneline", false, "Print the current conditions on one line, for shell prompts (same as --format oneline)")
+		flags.StringVar(&cfg.Oneline.Template, "oneline-template", cfg.Oneline.Template, "Template for --oneline, waybar and i3bar, e.g. \"{icon} {temp}{unit} {wind_dir}\"")
+		flags.StringVar(&cfg.Oneline.Glyphs, "glyphs", cfg.Oneline.Glyphs, "Condition glyphs for the {icon} field: unicode or nerd")
+	} else {
+		flags.StringVar(&cfg.Format, "format", cfg.Format, "Output format: text, json, yaml or csv")
+	}
+	flags.StringVar(&templateText, "template", "", "Go text/template to print the forecast with instead of the text output")
+	flags.StringVar(&cfg.Template, "template-file", cfg.Template, "File holding the --template, used unless --format says otherwise")
+	flags.BoolVar(&noCache, "no-cache", !cfg.Cache.Enabled, "Don't read or write the response cache")
+	flags.BoolVar(&refresh, "refresh", false, "Ignore cached responses but update the cache")
+	flags.BoolVar(&noColor, "no-color", !cfg.Color, "Don't color the output")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if days < 0 || hours < 0 {
+		return fmt.Errorf("--days and --hours can't be negative")
+	}
+	if locateOpts.MatchIndex < 0 {
+		return fmt.Errorf("--match-index can't be negative")
+	}
+
+	if flags.NArg() > 0 {
+		if command.Name == "" {
+			return fmt.Errorf("unknown command %q, run weather -h for the list", flags.Arg(0))
+		}
+		return fmt.Errorf("unexpected argument %q, give the location with -l", flags.Arg(0))
+	}
+
+	if version {
+		fmt.Println(VERSION)
+		return nil
+	}
+
+	// The flags have the last word on the cache and color settings.
+	render.Colorizer.Disable = noColor
+	responseCache = nil
+	if !noCache {
+		setupCache(cfg, refresh)
+	}
+
+	// Formats and templates from the config fall back to text where they
+	// don't fit, given as flags they're a mistake.
+	var formatFlag bool
+	var templateFlag string
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "format", "oneline":
+			formatFlag = true
+		case "template", "template-file":
+			templateFlag = f.Name
+		}
+	})
+
+	if oneline {
+		cfg.Format = "oneline"
+	}
+	lineFormat := slices.Contains(render.LineFormats, cfg.Format)
+	if lineFormat && !command.Current {
+		if formatFlag {
+			return fmt.Errorf("--format %s shows the current conditions, use weather now", cfg.Format)
+		}
+		cfg.Format, lineFormat = render.DefaultFormat, false
+	}
+	if lineFormat {
+		if _, ok := render.GlyphSets[cfg.Oneline.Glyphs]; !ok {
+			return fmt.Errorf("unknown glyphs %q, must be one of: %s", cfg.Oneline.Glyphs, strings.Join(slices.Sorted(maps.Keys(render.GlyphSets)), ", "))
+		}
+		if err := render.ValidLineTemplate(cfg.Oneline.Template); err != nil {
+			return err
+		}
+	} else if err := render.ValidFormat(cfg.Format); err != nil {
+		return err
+	}
+
+	// A configured template file only replaces the text output.
+	var tmpl *template.Template
+	if templateFlag != "" && cfg.Format != render.DefaultFormat {
+		return fmt.Errorf("--%s can't be used with --format %s", templateFlag, cfg.Format)
+	}
+	if cfg.Format == render.DefaultFormat && (templateText != "" || cfg.Template != "") {
+		if templateText == "" {
+			b, err := os.ReadFile(cfg.Template)
+			if err != nil {
+				return fmt.Errorf("reading the template failed: %s", err.Error())
+			}
+			templateText = string(b)
+		}
+		var err error
+		if tmpl, err = render.ParseTemplate(templateText); err != nil {
+			return err
+		}
+	}
+
+	services, err := newServices(cfg)
+	if err != nil {
+		return err
+	}
+	if pick {
+		locateOpts.Pick = pickLocation
+	}
+
+	// --lat/--lon and --places replace the configured default location, but
+	// add to any -l flags
+	explicit := locations.set
+	if lat != "" || lon != "" {
+		if _, _, ok := geocode.ParseCoordinates(lat + "," + lon); !ok {
+			return fmt.Errorf("--lat and --lon must both be given as decimal degrees")
+		}
+		if !explicit {
+			queries = nil
+		}
+		explicit = true
+		queries = append(queries, lat+","+lon)
+	}
+	if placeNames != "" {
+		places, err := loadPlaces()
+		if err != nil {
+			return err
+		}
+		names, err := queriesForPlaces(places, placeNames)
+		if err != nil {
+			return err
+		}
+		if !explicit {
+			queries = nil
+		}
+		queries = append(queries, names...)
+	}
+
+	// Only the current conditions need a single day, a daily forecast on
+	// its own shows however many were asked for.
+	if command.Current && days <= 1 {
+		days = 0
+	}
+
+	if len(queries) > 1 {
+		if lineFormat && formatFlag {
+			return fmt.Errorf("--format %s shows one location at a time", cfg.Format)
+		}
+		if lineFormat {
+			cfg.Format = render.DefaultFormat
+		}
+		if templateFlag != "" {
+			return fmt.Errorf("--%s shows one location at a time", templateFlag)
+		}
+		if !command.Current && !command.Daily {
+			return fmt.Errorf("weather %s shows one location at a time", command.Name)
+		}
+		return compare(services, queries, locateOpts, cfg, days, ignoreAlerts)
+	}
+
+	if len(queries) == 1 {
+		cfg.Location = queries[0]
+	}
+
+	lookup := weather.LookupOptions{Units: cfg.Units, Locate: locateOpts}
+
+	if lineFormat {
+		// Prompts and status bars run this every few seconds, so always ask
+		// for the same sections and let the cache answer between refreshes.
+		lookup.Exclude = []string{"minutely", "hourly"}
+		result, err := services.Lookup(context.Background(), cfg.Location, lookup)
+		if err != nil {
+			return err
+		}
+		if ignoreAlerts {
+			result.Forecast.Alerts = nil
+		}
+
+		line, err := render.NewLine(cfg.Oneline.Template, result.Forecast, result.Location, result.Units, render.GlyphSets[cfg.Oneline.Glyphs])
+		if err != nil {
+			return err
+		}
+		return render.WriteLine(os.Stdout, cfg.Format, line)
+	}
+
+	// Don't make the provider send data nobody is going to see.
+	if !nowcast {
+		lookup.Exclude = append(lookup.Exclude, "minutely")
+	}
+	if hours == 0 {
+		lookup.Exclude = append(lookup.Exclude, "hourly")
+	}
+	if days == 0 {
+		lookup.Exclude = append(lookup.Exclude, "daily")
+	}
+
+	result, err := services.Lookup(context.Background(), cfg.Location, lookup)
+	if err != nil {
+		return err
+	}
+
+	if cfg.Format != render.DefaultFormat {
+		report := render.NewReport(result.Forecast, result.Location, result.Units, days, hours, nowcast, ignoreAlerts)
+		return render.WriteReport(os.Stdout, cfg.Format, report)
+	}
+
+	if tmpl != nil {
+		data := render.NewTemplateData(result.Forecast, result.Location, result.Units, days, hours, nowcast, ignoreAlerts)
+		return render.WriteTemplate(os.Stdout, tmpl, data)
+	}
+
+	if command.Current {
+		render.WriteCurrent(os.Stdout, result.Forecast, result.Location, result.Units, ignoreAlerts)
+	} else {
+		fmt.Println(colorize("[green]" + result.Location.DisplayName))
+	}
+
+	if nowcast {
+		render.WriteNowcast(os.Stdout, result.Forecast)
+	}
+
+	if hours > 0 {
+		render.WriteHourly(os.Stdout, result.Forecast, hours, verbose, result.Units)
+	}
+
+	if days > 0 {
+		render.WriteDaily(os.Stdout, result.Forecast, days, result.Units)
+	}
+
+	return nil
+}
+
+// compare runs the multi location comparison for repeated -l flags or --places.
+func compare(services weather.Services, queries []string, locateOpts geocode.LocateOptions, cfg Config, days int, ignoreAlerts bool) error {
+	// Prompts for several locations at once would be a mess, filters only.
+	locateOpts.Pick = nil
+	locateOpts.MatchIndex = 0
+
+	results, units, err := compareLocations(context.Background(), services, queries, locateOpts, cfg.Units)
+	if err != nil {
+		return err
+	}
+
+	if cfg.Format == render.DefaultFormat {
+		render.WriteComparison(os.Stdout, results, days, units)
+		return nil
+	}
+
+	var reports []render.Report
+	for _, result := range results {
+		if result.Err != nil {
+			printError(fmt.Errorf("%s: %s", result.Query, result.Err.Error()))
+			continue
+		}
+		reports = append(reports, render.NewReport(result.Forecast, result.Location, units, days, 0, false, ignoreAlerts))
+	}
+
+	return render.WriteReports(os.Stdout, cfg.Format, reports)
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/jptoto/weather/internal/fixtures"
+)
+
+func TestForecastCommandFlags(t *testing.T) {
+	server := fixtures.NewServer(t)
+	cfg := fixtureConfig(t, server)
+	cfg.Location = "Berwyn, PA"
+	cfg.Format = "json"
+
+	tests := []struct {
+		command forecastCommand
+		args    []string
+		wantErr string
+		// with days = 3 in the config, output mustn't contain notWant
+		configDays int
+		notWant    string
+	}{
+		{weatherCommand, []string{"--days", "3", "--hours", "2", "--nowcast"}, "", 0, ""},
+		{nowCommand, []string{"--nowcast"}, "", 0, ""},
+		{nowCommand, []string{"--days", "3"}, "flag provided but not defined: -days", 0, ""},
+		{hourlyCommand, []string{"--hours", "3", "--verbose"}, "", 0, ""},
+		{hourlyCommand, []string{"--days", "3"}, "flag provided but not defined: -days", 0, ""},
+		{hourlyCommand, []string{"-l", "Berwyn, PA", "-l", "Berwyn, IL"}, "one location at a time", 0, ""},
+		{dailyCommand, []string{"-d", "3"}, "", 0, ""},
+		{dailyCommand, []string{"--hours", "3"}, "flag provided but not defined: -hours", 0, ""},
+		{dailyCommand, []string{"--days", "-1"}, "can't be negative", 0, ""},
+		{hourlyCommand, []string{"--hours", "-1"}, "can't be negative", 0, ""},
+		{nowCommand, []string{"--match-index", "-1"}, "--match-index can't be negative", 0, ""},
+		{weatherCommand, []string{"daily"}, `unknown command "daily"`, 0, ""},
+		{nowCommand, []string{"--format", "text"}, "", 3, "Day Forecast"},
+		{hourlyCommand, []string{"--format", "text", "--hours", "1"}, "", 3, "Day Forecast"},
+		{weatherCommand, []string{"--format", "text"}, "", 3, "Hour Forecast"},
+		{nowCommand, []string{"--oneline"}, "", 0, ""},
+		{nowCommand, []string{"--format", "waybar", "--glyphs", "nerd", "--oneline-template", "{icon} {temp}"}, "", 0, ""},
+		{nowCommand, []string{"--oneline", "--oneline-template", "{temperature}"}, "unknown field {temperature}", 0, ""},
+		{nowCommand, []string{"--oneline", "--glyphs", "ascii"}, `unknown glyphs "ascii"`, 0, ""},
+		{nowCommand, []string{"--oneline", "-l", "Berwyn, PA", "-l", "Berwyn, IL"}, "one location at a time", 0, ""},
+		{dailyCommand, []string{"--oneline"}, "flag provided but not defined: -oneline", 0, ""},
+		{hourlyCommand, []string{"--format", "text", "--template", "{{range .Hourly}}{{round .Temperature}}\n{{end}}"}, "", 0, ""},
+		{nowCommand, []string{"--format", "text", "--template", "{{.Current.Temp}}"}, "can't evaluate field Temp", 0, ""},
+		{nowCommand, []string{"--format", "text", "--template", "{{"}, "parsing the template failed", 0, ""},
+		{nowCommand, []string{"--format", "text", "--template-file", "missing.tmpl"}, "reading the template failed", 0, ""},
+		{dailyCommand, []string{"--format", "json", "--template", "{{.Daily}}"}, "--template can't be used with --format json", 0, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.command.Name+" "+strings.Join(test.args, " "), func(t *testing.T) {
+			cfg := cfg
+			cfg.Days = test.configDays
+			var err error
+			output := capture(t, func() { err = runForecast(cfg, test.command, test.args) })
+
+			switch {
+			case test.wantErr == "" && err != nil:
+				t.Errorf("got %v, want success", err)
+			case test.wantErr != "" && (err == nil || !strings.Contains(err.Error(), test.wantErr)):
+				t.Errorf("got error %v, want one containing %q", err, test.wantErr)
+			}
+			if test.notWant != "" && strings.Contains(output, test.notWant) {
+				t.Errorf("got %q in the output:\n%s", test.notWant, output)
+			}
+		})
+	}
+}
+
+func TestConfiguredLineFormat(t *testing.T) {
+	server := fixtures.NewServer(t)
+	cfg := fixtureConfig(t, server)
+	cfg.Location = "Berwyn, PA"
+	cfg.Format = "oneline"
+
+	tests := []struct {
+		name    string
+		run     func(cfg Config, args []string) error
+		args    []string
+		want    string
+		wantErr string
+	}{
+		{"now", runNow, nil, "°F", ""},
+		{"daily falls back to text", runDaily, []string{"-d", "2"}, "2 Day Forecast", ""},
+		{"comparison falls back to text", runNow, []string{"-l", "Berwyn, PA", "-l", "Berwyn, IL"}, "Berwyn, IL", ""},
+		{"alerts fall back to text", runAlerts, nil, "Flood Watch", ""},
+		{"daily --format oneline", runDaily, []string{"--format", "oneline"}, "", "shows the current conditions"},
+		{"hourly --format waybar", runHourly, []string{"--format", "waybar"}, "", "shows the current conditions"},
+		{"now --oneline comparison", runNow, []string{"--oneline", "-l", "Berwyn, PA", "-l", "Berwyn, IL"}, "", "one location at a time"},
+		{"alerts --format oneline", runAlerts, []string{"--format", "oneline"}, "", "isn't supported for alerts"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var err error
+			output := capture(t, func() { err = test.run(cfg, test.args) })
+
+			switch {
+			case test.wantErr == "" && err != nil:
+				t.Errorf("got %v, want success", err)
+			case test.wantErr != "" && (err == nil || !strings.Contains(err.Error(), test.wantErr)):
+				t.Errorf("got error %v, want one containing %q", err, test.wantErr)
+			case !strings.Contains(output, test.want):
+				t.Errorf("got output without %q:\n%s", test.want, output)
+			}
+		})
+	}
+}
+
+// capture returns what fn prints to stdout, throwing away stderr.
+func capture(t *testing.T, fn func()) string {
+	t.Helper()
+
+	out, err := os.CreateTemp(t.TempDir(), "stdout")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+
+	silence(t)
+	os.Stdout = out
+	fn()
+
+	b, err := os.ReadFile(out.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(b)
+}
+
+// silence throws away what the test prints to stdout and stderr.
+func silence(t *testing.T) {
+	t.Helper()
+
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, stderr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = devNull, devNull
+	t.Cleanup(func() {
+		os.Stdout, os.Stderr = stdout, stderr
+		devNull.Close()
+	})
+}
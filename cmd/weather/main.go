@@ -0,0 +1,124 @@
+// Command weather prints the forecast for a location, and with subcommands
+// manages saved places, watches alerts, sends notifications and serves
+// forecasts over HTTP.
+package main
+
+import (
+	"flag"
+	"os"
+	"time"
+
+	"github.com/jptoto/weather"
+	"github.com/jptoto/weather/cache"
+	"github.com/jptoto/weather/forecast"
+	"github.com/jptoto/weather/geocode"
+	"github.com/jptoto/weather/httpclient"
+	"github.com/jptoto/weather/render"
+)
+
+// responseCache is shared by every service, nil when caching is off.
+var responseCache *cache.Cache
+
+const VERSION = "v0.1.0"
+
+// Subcommands run instead of the full forecast when named as the first
+// argument. They get the loaded config and the remaining arguments. config is
+// handled before the config is loaded, so a broken one can still be fixed.
+var Subcommands = map[string]func(cfg Config, args []string) error{
+	"now":      runNow,
+	"hourly":   runHourly,
+	"daily":    runDaily,
+	"places":   runPlaces,
+	"alerts":   runAlerts,
+	"notify":   runNotify,
+	"serve":    runServe,
+	"exporter": runExporter,
+}
+
+func main() {
+	args := os.Args[1:]
+
+	// "weather help <command>" is "weather <command> -h"
+	if len(args) > 0 && args[0] == "help" {
+		args = append(args[1:min(2, len(args))], "-h")
+	}
+
+	// Errors can come before the config is loaded, so honor NO_COLOR now.
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		render.Colorizer.Disable = true
+	}
+
+	if len(args) > 0 && args[0] == "config" {
+		if err := runConfig(args[1:]); err != nil {
+			printError(err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		printError(err)
+		os.Exit(1)
+	}
+
+	httpclient.Default = httpclient.New(cfg.Http.Timeout.Duration, cfg.Http.Retries)
+
+	run := runWeather
+	if len(args) > 0 && Subcommands[args[0]] != nil {
+		run, args = Subcommands[args[0]], args[1:]
+	}
+
+	if cfg.Cache.Enabled {
+		setupCache(cfg, false)
+	}
+	render.Colorizer.Disable = !cfg.Color
+
+	if err := run(cfg, args); err != nil && err != flag.ErrHelp {
+		printError(err)
+		os.Exit(1)
+	}
+}
+
+// setupCache points the process wide response cache at the configured directory.
+// Failing to find a cache directory just means running without one.
+func setupCache(cfg Config, refresh bool) {
+	c, err := cache.New(cfg.Cache.Dir, map[string]time.Duration{
+		"forecast": cfg.Cache.ForecastTTL.Duration,
+		"geocode":  cfg.Cache.GeocodeTTL.Duration,
+		"ip":       cfg.Cache.IpTTL.Duration,
+	})
+	if err != nil {
+		printError(err)
+		return
+	}
+
+	c.Refresh = refresh
+	responseCache = c
+}
+
+// newServices builds the forecast provider, geocoder and ip locator picked in
+// the config, wrapped in the response cache and saved places.
+func newServices(cfg Config) (services weather.Services, err error) {
+	services, err = weather.NewServices(weather.Options{
+		Provider:         cfg.Provider,
+		ProviderOptions:  forecast.ProviderOptions{ApiKey: cfg.ApiKeys.OpenWeatherMap, BaseUrl: cfg.ProviderUrl},
+		Geocoder:         cfg.Geocoder,
+		GeocoderOptions:  geocode.GeocoderOptions{BaseUrl: cfg.GeocoderUrl, ApiKey: cfg.ApiKeys.Geocoding, GazetteerDir: cfg.Gazetteer},
+		IpLocator:        cfg.IpLocator,
+		IpLocatorOptions: geocode.IpLocatorOptions{IpLookupUrl: cfg.IpLookupUrl, GeoLookupUrl: cfg.IpGeoUrl},
+		Cache:            responseCache,
+	})
+	if err != nil {
+		return
+	}
+
+	// Saved places win over any geocoder, and never touch the network.
+	places, err := loadPlaces()
+	if err != nil {
+		return
+	}
+	services.Geocoder = geocode.FallbackGeocoder{places, services.Geocoder}
+
+	return services, nil
+}
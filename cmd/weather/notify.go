@@ -0,0 +1,440 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jptoto/weather"
+	"github.com/jptoto/weather/forecast"
+	"github.com/jptoto/weather/geocode"
+	"github.com/jptoto/weather/httpclient"
+	"github.com/jptoto/weather/render"
+)
+
+// A Notification is one thing worth telling someone about: a newly issued or
+// updated alert, or a forecast crossing one of the configured thresholds.
+type Notification struct {
+	Kind     string    `json:"kind"`
+	Location string    `json:"location"`
+	Title    string    `json:"title"`
+	Message  string    `json:"message"`
+	Severity string    `json:"severity,omitempty"`
+	Time     time.Time `json:"time"`
+}
+
+// A Notifier delivers notifications somewhere.
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
+
+// Notifiers build a Notifier from its target: a url for webhooks, a command
+// line for command hooks.
+var Notifiers = map[string]func(target string) Notifier{
+	"webhook": func(target string) Notifier { return Webhook{Url: target} },
+	"slack":   func(target string) Notifier { return Slack{Url: target} },
+	"command": func(target string) Notifier { return CommandHook{Command: target} },
+}
+
+// Webhook POSTs each Notification as JSON.
+type Webhook struct {
+	Url string
+}
+
+func (w Webhook) Notify(ctx context.Context, n Notification) error {
+	return httpclient.Default.PostJson(ctx, w.Url, n)
+}
+
+// Slack POSTs to a Slack incoming webhook, or anything else that accepts
+// Slack's {"text": ...} messages such as Mattermost or Discord's /slack url.
+type Slack struct {
+	Url string
+}
+
+func (s Slack) Notify(ctx context.Context, n Notification) error {
+	return httpclient.Default.PostJson(ctx, s.Url, map[string]string{
+		"text": fmt.Sprintf("*%s* (%s)\n%s", n.Title, n.Location, n.Message),
+	})
+}
+
+// CommandHook runs a local command for each notification, e.g. notify-send.
+// The notification is passed as JSON on stdin and in WEATHER_* environment
+// variables. The command is split on spaces, not run through a shell.
+type CommandHook struct {
+	Command string
+}
+
+func (c CommandHook) Notify(ctx context.Context, n Notification) error {
+	args := strings.Fields(c.Command)
+	if len(args) == 0 {
+		return fmt.Errorf("empty notify command")
+	}
+
+	b, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stdin = bytes.NewReader(b)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(),
+		"WEATHER_KIND="+n.Kind,
+		"WEATHER_LOCATION="+n.Location,
+		"WEATHER_TITLE="+n.Title,
+		"WEATHER_MESSAGE="+n.Message,
+		"WEATHER_SEVERITY="+n.Severity,
+	)
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("running notify command failed: %s", err.Error())
+	}
+
+	return nil
+}
+
+// A Rule is a threshold like "gust > 15". Rules are checked against the hourly
+// forecast in si units: °C, m/s and mm, with pop, humidity and clouds in
+// percent.
+type Rule struct {
+	Field     string
+	Op        string
+	Threshold float64
+}
+
+// RuleFields pull the value a rule compares out of an hour of forecast.
+var RuleFields = map[string]func(forecast.HourlyWeather) float64{
+	"temp":       func(h forecast.HourlyWeather) float64 { return h.Temperature },
+	"feels_like": func(h forecast.HourlyWeather) float64 { return h.FeelsLike },
+	"wind":       func(h forecast.HourlyWeather) float64 { return h.WindSpeed },
+	"gust":       func(h forecast.HourlyWeather) float64 { return h.WindGust },
+	"pop":        func(h forecast.HourlyWeather) float64 { return h.Pop * 100 },
+	"humidity":   func(h forecast.HourlyWeather) float64 { return float64(h.Humidity) },
+	"clouds":     func(h forecast.HourlyWeather) float64 { return float64(h.Clouds) },
+	"uvi":        func(h forecast.HourlyWeather) float64 { return h.Uvi },
+}
+
+var ruleOps = []string{"<=", ">=", "<", ">"}
+
+func parseRule(s string) (rule Rule, err error) {
+	for _, op := range ruleOps {
+		field, threshold, ok := strings.Cut(s, op)
+		if !ok {
+			continue
+		}
+
+		rule.Field = strings.ToLower(strings.TrimSpace(field))
+		rule.Op = op
+		if _, ok := RuleFields[rule.Field]; !ok {
+			return rule, fmt.Errorf("unknown rule field %q in %q, must be one of: %s", rule.Field, s, strings.Join(slices.Sorted(maps.Keys(RuleFields)), ", "))
+		}
+		rule.Threshold, err = strconv.ParseFloat(strings.TrimSpace(threshold), 64)
+		if err != nil {
+			return rule, fmt.Errorf("rule %q needs a number to compare with", s)
+		}
+
+		return rule, nil
+	}
+
+	return rule, fmt.Errorf("rule %q must look like \"temp < 0\" or \"gust > 15\"", s)
+}
+
+func (r Rule) String() string {
+	return fmt.Sprintf("%s %s %v", r.Field, r.Op, r.Threshold)
+}
+
+func (r Rule) Match(hour forecast.HourlyWeather) bool {
+	value := RuleFields[r.Field](hour)
+
+	switch r.Op {
+	case "<":
+		return value < r.Threshold
+	case "<=":
+		return value <= r.Threshold
+	case ">":
+		return value > r.Threshold
+	case ">=":
+		return value >= r.Threshold
+	}
+
+	return false
+}
+
+// NotifyState remembers what has already been sent, by key, so the same alert
+// or threshold crossing isn't sent twice across runs.
+type NotifyState map[string]time.Time
+
+// notifyStateMaxAge is how long sent keys are remembered. Alerts and forecast
+// hours older than this can't come round again.
+const notifyStateMaxAge = 14 * 24 * time.Hour
+
+// notifyStatePath keeps the state in the cache directory, it's safe to lose.
+func notifyStatePath(cfg Config) (string, error) {
+	dir := cfg.Cache.Dir
+	if dir == "" {
+		cacheHome, err := os.UserCacheDir()
+		if err != nil {
+			return "", fmt.Errorf("finding the cache directory failed: %s", err.Error())
+		}
+		dir = filepath.Join(cacheHome, "weather")
+	}
+
+	return filepath.Join(dir, "notified.json"), nil
+}
+
+func loadNotifyState(path string) (NotifyState, error) {
+	state := NotifyState{}
+
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return state, fmt.Errorf("reading notify state %s failed: %s", path, err.Error())
+	}
+
+	if err := json.Unmarshal(b, &state); err != nil {
+		return state, fmt.Errorf("decoding notify state %s failed: %s", path, err.Error())
+	}
+
+	return state, nil
+}
+
+func (s NotifyState) save(path string) error {
+	for key, sent := range s {
+		if time.Since(sent) > notifyStateMaxAge {
+			delete(s, key)
+		}
+	}
+
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, b, 0o644)
+}
+
+// alertNotifications turns alerts into notifications, keyed so an updated alert
+// is sent again but an unchanged one isn't.
+func alertNotifications(alerts []forecast.Alerts, location string, loc *time.Location) (keys []string, notifications []Notification) {
+	for _, alert := range alerts {
+		keys = append(keys, "alert|"+location+"|"+forecast.AlertId(alert)+"|"+forecast.AlertFingerprint(alert))
+		notifications = append(notifications, Notification{
+			Kind:     "alert",
+			Location: location,
+			Title:    alert.Event,
+			Message: fmt.Sprintf("%s, until %s", alert.SenderName,
+				forecast.TimeIn(alert.End, loc).Format("January 2 at 3:04pm MST")),
+			Severity: forecast.ClassifyAlert(alert).String(),
+			Time:     time.Unix(alert.Start, 0),
+		})
+	}
+
+	return keys, notifications
+}
+
+// ruleNotifications finds the first hour within the next lookahead hours each
+// rule matches. A crossing is sent once per rule per local day.
+func ruleNotifications(rules []Rule, hourly []forecast.HourlyWeather, lookahead int, location string, loc *time.Location) (keys []string, notifications []Notification) {
+	hours := hourly[:max(0, min(lookahead, len(hourly)))]
+
+	for _, rule := range rules {
+		for _, hour := range hours {
+			if !rule.Match(hour) {
+				continue
+			}
+
+			when := forecast.TimeIn(hour.Dt, loc)
+			keys = append(keys, "rule|"+location+"|"+rule.String()+"|"+when.Format("2006-01-02"))
+			notifications = append(notifications, Notification{
+				Kind:     "threshold",
+				Location: location,
+				Title:    "Forecast " + rule.String(),
+				Message:  fmt.Sprintf("%s is forecast to be %v at %s", rule.Field, render.Round(RuleFields[rule.Field](hour), 1), when.Format("Mon 3pm")),
+				Time:     when,
+			})
+			break
+		}
+	}
+
+	return keys, notifications
+}
+
+const notifyUsage = `usage: weather notify [flags]
+
+Send new alerts and forecast threshold crossings to a webhook, Slack or a local
+command. Anything already sent is remembered and not sent again.
+
+flags:
+`
+
+// runNotify implements `weather notify`.
+func runNotify(cfg Config, args []string) error {
+	flags := flag.NewFlagSet("notify", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), notifyUsage)
+		flags.PrintDefaults()
+	}
+
+	var watch bool
+	var dryRun bool
+	var interval time.Duration
+	flags.StringVar(&cfg.Location, "location", cfg.Location, "Location or saved place name to watch")
+	flags.StringVar(&cfg.Location, "l", cfg.Location, "Location or saved place name to watch (shorthand)")
+	flags.Var(&stringList{values: &cfg.Notify.Rules}, "rule", "Threshold rule like \"temp < 0\" or \"gust > 15\" in si units, repeatable")
+	flags.StringVar(&cfg.Notify.MinSeverity, "min-severity", cfg.Notify.MinSeverity, "Only send alerts at least this severe: "+strings.Join(forecast.SeverityNames, ", "))
+	flags.IntVar(&cfg.Notify.Lookahead, "lookahead", cfg.Notify.Lookahead, "No. of forecast hours rules are checked against")
+	flags.StringVar(&cfg.Notify.Webhook, "webhook", cfg.Notify.Webhook, "Url to POST each notification to as JSON")
+	flags.StringVar(&cfg.Notify.Slack, "slack", cfg.Notify.Slack, "Slack compatible incoming webhook url")
+	flags.StringVar(&cfg.Notify.Command, "command", cfg.Notify.Command, "Command to run for each notification, it gets the JSON on stdin")
+	flags.BoolVar(&dryRun, "dry-run", false, "Print notifications instead of sending them, and don't remember them")
+	flags.BoolVar(&watch, "watch", false, "Keep polling and notify as things change")
+	flags.DurationVar(&interval, "interval", 10*time.Minute, "How often --watch polls")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if cfg.Notify.Lookahead < 0 {
+		return fmt.Errorf("--lookahead can't be negative")
+	}
+
+	minSeverity, err := forecast.ParseSeverity(cfg.Notify.MinSeverity)
+	if err != nil {
+		return err
+	}
+
+	var rules []Rule
+	for _, s := range cfg.Notify.Rules {
+		rule, err := parseRule(s)
+		if err != nil {
+			return err
+		}
+		rules = append(rules, rule)
+	}
+
+	targets := map[string]string{"webhook": cfg.Notify.Webhook, "slack": cfg.Notify.Slack, "command": cfg.Notify.Command}
+	var notifiers []Notifier
+	for _, name := range slices.Sorted(maps.Keys(targets)) {
+		if targets[name] != "" {
+			notifiers = append(notifiers, Notifiers[name](targets[name]))
+		}
+	}
+	if len(notifiers) == 0 && !dryRun {
+		return fmt.Errorf("nowhere to send notifications, give --webhook, --slack or --command, or set them under [notify] in the config file")
+	}
+
+	if responseCache != nil {
+		responseCache.Refresh = true
+	}
+
+	services, err := newServices(cfg)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	geolocation, err := geocode.Locate(ctx, services.Geocoder, services.IpLocator, cfg.Location)
+	if err != nil {
+		return err
+	}
+
+	lookup := weather.LookupOptions{
+		Units:   "si",
+		Exclude: []string{"current", "minutely", "daily"},
+	}
+	if len(rules) == 0 {
+		lookup.Exclude = append(lookup.Exclude, "hourly")
+	}
+
+	statePath, err := notifyStatePath(cfg)
+	if err != nil {
+		return err
+	}
+	state, err := loadNotifyState(statePath)
+	if err != nil {
+		return err
+	}
+
+	for {
+		result, err := services.Forecast(ctx, geolocation, lookup)
+		if ctx.Err() != nil {
+			// interrupted mid request
+			return nil
+		}
+		if err != nil && !watch {
+			return err
+		}
+		if err != nil {
+			printError(err)
+		} else {
+			f := result.Forecast
+			loc := f.Location()
+
+			keys, notifications := alertNotifications(forecast.FilterAlerts(f.Alerts, minSeverity, ""), geolocation.DisplayName, loc)
+			ruleKeys, ruleNotes := ruleNotifications(rules, f.Hourly, cfg.Notify.Lookahead, geolocation.DisplayName, loc)
+			keys = append(keys, ruleKeys...)
+			notifications = append(notifications, ruleNotes...)
+
+			for i, n := range notifications {
+				if _, sent := state[keys[i]]; sent {
+					continue
+				}
+
+				if dryRun {
+					fmt.Println(colorize("[yellow]"+n.Title) + ": " + n.Message)
+					state[keys[i]] = time.Now()
+					continue
+				}
+
+				delivered := false
+				for _, notifier := range notifiers {
+					if err := notifier.Notify(ctx, n); err != nil {
+						printError(err)
+						continue
+					}
+					delivered = true
+				}
+				// Only remember it once it got somewhere, so a failed send is
+				// retried on the next run.
+				if delivered {
+					state[keys[i]] = time.Now()
+				}
+			}
+
+			if !dryRun {
+				if err := state.save(statePath); err != nil {
+					return err
+				}
+			}
+		}
+
+		if !watch {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(interval):
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jptoto/weather"
+	"github.com/jptoto/weather/internal/fixtures"
+)
+
+func TestParseRule(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    Rule
+		wantErr string
+	}{
+		{"temp < 0", Rule{"temp", "<", 0}, ""},
+		{"gust>15", Rule{"gust", ">", 15}, ""},
+		{" POP >= 80 ", Rule{"pop", ">=", 80}, ""},
+		{"feels_like <= -10.5", Rule{"feels_like", "<=", -10.5}, ""},
+		{"snow > 1", Rule{}, "unknown rule field \"snow\""},
+		{"temp < cold", Rule{}, "needs a number"},
+		{"temp = 0", Rule{}, "must look like"},
+		{"", Rule{}, "must look like"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.spec, func(t *testing.T) {
+			got, err := parseRule(test.spec)
+			switch {
+			case test.wantErr == "" && err != nil:
+				t.Errorf("got %v, want %v", err, test.want)
+			case test.wantErr != "" && (err == nil || !strings.Contains(err.Error(), test.wantErr)):
+				t.Errorf("got error %v, want one containing %q", err, test.wantErr)
+			case test.wantErr == "" && got != test.want:
+				t.Errorf("got %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestRuleNotifications(t *testing.T) {
+	server := fixtures.NewServer(t)
+	services, err := newServices(fixtureConfig(t, server))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := services.Lookup(context.Background(), "Berwyn, PA", weather.LookupOptions{Units: "si"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := result.Forecast
+
+	tests := []struct {
+		rules     []string
+		lookahead int
+		want      []string
+	}{
+		{[]string{"temp > 0"}, 12, []string{"rule|Berwyn|temp > 0|2025-04-25"}},
+		{[]string{"temp < -40", "humidity > 100"}, 48, nil},
+		{[]string{"temp > 0"}, 0, nil},
+		{[]string{"temp > 0"}, -1, nil},
+	}
+
+	for _, test := range tests {
+		var rules []Rule
+		for _, spec := range test.rules {
+			rule, err := parseRule(spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rules = append(rules, rule)
+		}
+
+		keys, notifications := ruleNotifications(rules, f.Hourly, test.lookahead, "Berwyn", f.Location())
+		if strings.Join(keys, "\n") != strings.Join(test.want, "\n") {
+			t.Errorf("%v within %d hours: got keys %q, want %q", test.rules, test.lookahead, keys, test.want)
+		}
+		if len(notifications) != len(keys) {
+			t.Errorf("got %d notifications for %d keys", len(notifications), len(keys))
+		}
+	}
+}
+
+func TestNotifySendsOnce(t *testing.T) {
+	var mu sync.Mutex
+	var received []Notification
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var n Notification
+		if err := json.NewDecoder(r.Body).Decode(&n); err != nil {
+			t.Error(err)
+		}
+		mu.Lock()
+		received = append(received, n)
+		mu.Unlock()
+	}))
+	defer hook.Close()
+
+	server := fixtures.NewServer(t)
+	cfg := fixtureConfig(t, server)
+	cfg.Location = "Berwyn, PA"
+	args := []string{"--webhook", hook.URL, "--rule", "temp > 0"}
+
+	if err := runNotify(cfg, args); err != nil {
+		t.Fatal(err)
+	}
+	first := len(received)
+	if first != 2 {
+		t.Fatalf("got %d notifications, want the flood watch and the rule: %+v", first, received)
+	}
+
+	// Nothing has changed, so the second run has nothing new to send.
+	if err := runNotify(cfg, args); err != nil {
+		t.Fatal(err)
+	}
+	if len(received) != first {
+		t.Errorf("second run sent %d more notifications, want none", len(received)-first)
+	}
+
+	// A new rule is new, the alert still isn't.
+	if err := runNotify(cfg, append(args, "--rule", "humidity > 0")); err != nil {
+		t.Fatal(err)
+	}
+	if len(received) != first+1 || received[first].Title != "Forecast humidity > 0" {
+		t.Errorf("got %+v after adding a rule, want just its notification", received[first:])
+	}
+}
+
+func TestNotifyRejectsNegativeLookahead(t *testing.T) {
+	server := fixtures.NewServer(t)
+	err := runNotify(fixtureConfig(t, server), []string{"--dry-run", "--lookahead", "-1"})
+	if err == nil || !strings.Contains(err.Error(), "can't be negative") {
+		t.Errorf("got %v, want an error about the negative lookahead", err)
+	}
+}
+
+func TestMaskUrl(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"", ""},
+		{"https://hooks.slack.com/services/T0/B0/abcdefgh", "https://hooks.slack.com/*******************efgh"},
+		{"http://localhost:9000/hook?token=s3cr3t", "http://localhost:9000/*************cr3t"},
+		{"not a url", "***** url"},
+	}
+
+	for _, test := range tests {
+		if got := maskUrl(test.url); got != test.want {
+			t.Errorf("maskUrl(%q) = %q, want %q", test.url, got, test.want)
+		}
+	}
+}
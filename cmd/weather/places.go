@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jptoto/weather/geocode"
+)
+
+// A Place is a named location saved with `weather places add`. The resolved
+// GeoLocation is stored with it so using the name never needs a geocoding call.
+type Place struct {
+	Name     string              `json:"name"`
+	Query    string              `json:"query"`
+	Location geocode.GeoLocation `json:"location"`
+	Added    time.Time           `json:"added"`
+}
+
+// Places are keyed by lower cased name, and also work as a Geocoder so saved
+// names can be used anywhere --location is.
+type Places map[string]Place
+
+// placesPath keeps places.json next to the config file.
+func placesPath() (string, error) {
+	path, err := configPath()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(filepath.Dir(path), "places.json"), nil
+}
+
+func loadPlaces() (Places, error) {
+	places := Places{}
+
+	path, err := placesPath()
+	if err != nil {
+		return places, err
+	}
+
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return places, nil
+	}
+	if err != nil {
+		return places, fmt.Errorf("reading places file %s failed: %s", path, err.Error())
+	}
+
+	if err := json.Unmarshal(b, &places); err != nil {
+		return places, fmt.Errorf("decoding places file %s failed: %s", path, err.Error())
+	}
+
+	return places, nil
+}
+
+func (p Places) save() error {
+	path, err := placesPath()
+	if err != nil {
+		return err
+	}
+
+	b, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, b, 0o644)
+}
+
+func (p Places) Get(name string) (Place, bool) {
+	place, ok := p[strings.ToLower(strings.TrimSpace(name))]
+	return place, ok
+}
+
+// Search returns the saved location for a place name, or nothing so the next
+// geocoder gets a turn.
+func (p Places) Search(ctx context.Context, query string) (locations geocode.GeoLocations, err error) {
+	if place, ok := p.Get(query); ok {
+		locations = append(locations, place.Location)
+	}
+
+	return locations, nil
+}
+
+const placesUsage = `usage: weather places <command>
+
+commands:
+  add <name> <location>  geocode location once and save it as name
+  rm <name>              forget a saved place
+  list                   show the saved places
+`
+
+// runPlaces implements `weather places add|rm|list`.
+func runPlaces(cfg Config, args []string) error {
+	if len(args) == 0 || isHelp(args[0]) {
+		fmt.Print(placesUsage)
+		return nil
+	}
+
+	places, err := loadPlaces()
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "add":
+		if len(args) < 3 {
+			return fmt.Errorf("usage: weather places add <name> <location>")
+		}
+		name := strings.ToLower(strings.TrimSpace(args[1]))
+		query := strings.Join(args[2:], " ")
+
+		services, err := newServices(cfg)
+		if err != nil {
+			return err
+		}
+
+		geolocation, err := geocode.Locate(context.Background(), services.Geocoder, services.IpLocator, query)
+		if err != nil {
+			return err
+		}
+
+		places[name] = Place{Name: name, Query: query, Location: geolocation, Added: time.Now()}
+		if err := places.save(); err != nil {
+			return err
+		}
+		fmt.Printf("Saved %s as %s\n", colorize("[green]"+geolocation.DisplayName), colorize("[cyan]"+name))
+	case "rm":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: weather places rm <name>")
+		}
+		name := strings.ToLower(strings.TrimSpace(args[1]))
+		if _, ok := places[name]; !ok {
+			return fmt.Errorf("no saved place named %q", name)
+		}
+		delete(places, name)
+		return places.save()
+	case "list":
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, name := range slices.Sorted(maps.Keys(places)) {
+			place := places[name]
+			fmt.Fprintf(w, "%s\t%s, %s\t%s\n", name, place.Location.Latitude, place.Location.Longitude, place.Location.DisplayName)
+		}
+		return w.Flush()
+	default:
+		fmt.Print(placesUsage)
+		return fmt.Errorf("unknown places command %q", args[0])
+	}
+
+	return nil
+}
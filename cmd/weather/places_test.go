@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/jptoto/weather/geocode"
+	"github.com/jptoto/weather/internal/fixtures"
+)
+
+// fixtureConfig points every service at server, with the config, cache, saved
+// places and gazetteer kept in a fresh temporary directory.
+func fixtureConfig(t *testing.T, server *fixtures.Server) Config {
+	t.Helper()
+
+	dir := t.TempDir()
+	t.Setenv("WEATHER_CONFIG", filepath.Join(dir, "config.toml"))
+
+	cfg := defaultConfig()
+	cfg.ProviderUrl = server.URL
+	cfg.GeocoderUrl = server.URL
+	cfg.IpLookupUrl = server.IpLookupUrl()
+	cfg.IpGeoUrl = server.IpGeoUrl()
+	cfg.Gazetteer = filepath.Join(dir, "gazetteer")
+	cfg.ApiKeys.OpenWeatherMap = fixtures.ApiKey
+	cfg.Cache.Enabled = false
+	cfg.Cache.Dir = filepath.Join(dir, "cache")
+
+	return cfg
+}
+
+func TestSavedPlacesSkipTheGeocoder(t *testing.T) {
+	server := fixtures.NewServer(t)
+	cfg := fixtureConfig(t, server)
+
+	places := Places{"home": {Name: "home", Query: "Berwyn, PA", Location: geocode.GeoLocation{Latitude: "1", Longitude: "2", DisplayName: "Home"}}}
+	if err := places.save(); err != nil {
+		t.Fatal(err)
+	}
+
+	services, err := newServices(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	geolocation, err := geocode.Locate(context.Background(), services.Geocoder, services.IpLocator, "Home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if geolocation.DisplayName != "Home" {
+		t.Errorf("got %q, want the saved place", geolocation.DisplayName)
+	}
+	if requests := server.Requests(); len(requests) > 0 {
+		t.Errorf("expected no requests, got %v", requests)
+	}
+}
@@ -0,0 +1,380 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"maps"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jptoto/weather"
+	"github.com/jptoto/weather/forecast"
+	"github.com/jptoto/weather/geocode"
+	"github.com/jptoto/weather/units"
+)
+
+// servedLocation is one location `weather serve` keeps a forecast for. The
+// forecast is held in standard units and converted per request.
+type servedLocation struct {
+	Name     string
+	Query    string
+	Location geocode.GeoLocation
+	Units    string
+	Forecast forecast.Forecast
+	Updated  time.Time
+	Err      error
+	Errors   int
+}
+
+// Server keeps forecasts for a fixed set of locations fresh and serves them
+// over HTTP.
+type Server struct {
+	Services weather.Services
+	Units    string
+
+	mu        sync.RWMutex
+	names     []string
+	locations map[string]*servedLocation
+}
+
+func NewServer(services weather.Services, unitSystem string, queries map[string]string) *Server {
+	s := &Server{
+		Services:  services,
+		Units:     unitSystem,
+		names:     slices.Sorted(maps.Keys(queries)),
+		locations: map[string]*servedLocation{},
+	}
+	for name, query := range queries {
+		s.locations[name] = &servedLocation{Name: name, Query: query}
+	}
+
+	return s
+}
+
+// Refresh fetches every location's forecast, locating any that haven't been
+// located yet. A location that fails keeps serving its last good forecast.
+func (s *Server) Refresh(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, name := range s.names {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+
+			s.mu.RLock()
+			served := *s.locations[name]
+			s.mu.RUnlock()
+
+			served.Err = s.refreshLocation(ctx, &served)
+			if served.Err == nil {
+				served.Updated = time.Now()
+			} else {
+				served.Errors++
+				printError(fmt.Errorf("refreshing %s failed: %s", name, served.Err.Error()))
+			}
+
+			s.mu.Lock()
+			*s.locations[name] = served
+			s.mu.Unlock()
+		}(name)
+	}
+	wg.Wait()
+}
+
+func (s *Server) refreshLocation(ctx context.Context, served *servedLocation) (err error) {
+	if served.Location.Latitude == "" {
+		served.Location, err = geocode.Locate(ctx, s.Services.Geocoder, s.Services.IpLocator, served.Query)
+		if err != nil {
+			return err
+		}
+		served.Units, err = units.Resolve(s.Units, served.Location.Address.CountryCode)
+		if err != nil {
+			return err
+		}
+	}
+
+	f, err := forecast.Get(ctx, s.Services.Provider, forecast.Request{
+		Latitude:  served.Location.Latitude,
+		Longitude: served.Location.Longitude,
+		Units:     served.Units,
+		Exclude:   []string{"minutely"},
+	})
+	if err != nil {
+		return err
+	}
+	served.Forecast = f
+
+	return nil
+}
+
+// Run refreshes every interval until ctx is done.
+func (s *Server) Run(ctx context.Context, interval time.Duration) {
+	for {
+		s.Refresh(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// ServeResponse is the envelope every forecast endpoint answers with. Data is
+// the current conditions, hourly or daily forecast, or alerts.
+type ServeResponse struct {
+	Name     string              `json:"name"`
+	Location geocode.GeoLocation `json:"location"`
+	Units    string              `json:"units"`
+	Updated  time.Time           `json:"updated"`
+	Data     interface{}         `json:"data"`
+}
+
+// ServeSections pick the part of a forecast each endpoint returns.
+var ServeSections = map[string]func(forecast.Forecast) interface{}{
+	"current": func(f forecast.Forecast) interface{} { return f.Currently },
+	"hourly":  func(f forecast.Forecast) interface{} { return f.Hourly },
+	"daily":   func(f forecast.Forecast) interface{} { return f.Daily },
+	"alerts": func(f forecast.Forecast) interface{} {
+		if f.Alerts == nil {
+			return []forecast.Alerts{}
+		}
+		return f.Alerts
+	},
+}
+
+// Handler routes:
+//
+//	GET /locations                  the served locations
+//	GET /locations/{name}/{section} a section for one location
+//	GET /{section}                  a section for the first location
+//	GET /metrics                    every location in Prometheus text format
+//
+// where section is current, hourly, daily or alerts. ?units= overrides the
+// location's unit system.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /locations", s.handleLocations)
+	mux.HandleFunc("GET /locations/{name}/{section}", s.handleSection)
+	mux.HandleFunc("GET /{section}", s.handleSection)
+	mux.HandleFunc("GET /metrics", s.handleMetrics)
+
+	return mux
+}
+
+func (s *Server) handleLocations(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	type locationStatus struct {
+		Name     string              `json:"name"`
+		Query    string              `json:"query"`
+		Location geocode.GeoLocation `json:"location"`
+		Updated  time.Time           `json:"updated"`
+		Error    string              `json:"error,omitempty"`
+	}
+
+	statuses := []locationStatus{}
+	for _, name := range s.names {
+		served := s.locations[name]
+		status := locationStatus{Name: name, Query: served.Query, Location: served.Location, Updated: served.Updated}
+		if served.Err != nil {
+			status.Error = served.Err.Error()
+		}
+		statuses = append(statuses, status)
+	}
+
+	writeJson(w, http.StatusOK, statuses)
+}
+
+func (s *Server) handleSection(w http.ResponseWriter, r *http.Request) {
+	section, ok := ServeSections[r.PathValue("section")]
+	if !ok {
+		writeJsonError(w, http.StatusNotFound, fmt.Errorf("unknown endpoint %q, must be one of: %s", r.PathValue("section"), strings.Join(slices.Sorted(maps.Keys(ServeSections)), ", ")))
+		return
+	}
+
+	name := strings.ToLower(r.PathValue("name"))
+	if name == "" && len(s.names) > 0 {
+		name = s.names[0]
+	}
+
+	s.mu.RLock()
+	served, ok := s.locations[name]
+	if ok {
+		copied := *served
+		served = &copied
+	}
+	s.mu.RUnlock()
+
+	if !ok {
+		writeJsonError(w, http.StatusNotFound, fmt.Errorf("not serving a location named %q", name))
+		return
+	}
+	if served.Updated.IsZero() {
+		err := errors.New("no forecast yet")
+		if served.Err != nil {
+			err = served.Err
+		}
+		writeJsonError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+
+	unitSystem := served.Units
+	if r.URL.Query().Has("units") {
+		if _, ok := units.Formats[r.URL.Query().Get("units")]; !ok {
+			writeJsonError(w, http.StatusBadRequest, fmt.Errorf("unknown units %q, must be one of: %s", r.URL.Query().Get("units"), strings.Join(slices.Sorted(maps.Keys(units.Formats)), ", ")))
+			return
+		}
+		unitSystem = r.URL.Query().Get("units")
+	}
+
+	writeJson(w, http.StatusOK, ServeResponse{
+		Name:     served.Name,
+		Location: served.Location,
+		Units:    unitSystem,
+		Updated:  served.Updated,
+		Data:     section(units.Convert(served.Forecast, unitSystem)),
+	})
+}
+
+func writeJson(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+func writeJsonError(w http.ResponseWriter, status int, err error) {
+	writeJson(w, status, map[string]string{"error": err.Error()})
+}
+
+// serveQueries works out the locations to serve, keyed by name: those given
+// with -l, else the configured ones, else every saved place, else the default
+// location.
+func serveQueries(cfg Config, flagged []string) (queries map[string]string, err error) {
+	queries = map[string]string{}
+
+	list := flagged
+	if len(list) == 0 {
+		list = cfg.Serve.Locations
+	}
+	for _, query := range list {
+		queries[strings.ToLower(strings.TrimSpace(query))] = query
+	}
+	if len(queries) > 0 {
+		return queries, nil
+	}
+
+	places, err := loadPlaces()
+	if err != nil {
+		return nil, err
+	}
+	for name := range places {
+		queries[name] = name
+	}
+	if len(queries) > 0 {
+		return queries, nil
+	}
+
+	queries["default"] = cfg.Location
+	return queries, nil
+}
+
+const serveUsage = `usage: weather serve [flags]
+
+Keep forecasts for a set of locations fresh and serve them as JSON:
+
+  GET /locations                   the served locations
+  GET /locations/{name}/current    current conditions
+  GET /locations/{name}/hourly     hourly forecast
+  GET /locations/{name}/daily      daily forecast
+  GET /locations/{name}/alerts     active alerts
+  GET /current, /hourly, ...       the same for the first location
+  GET /metrics                     Prometheus metrics, see weather exporter
+
+Add ?units=us|si|ca|uk to pick the unit system.
+
+flags:
+`
+
+// runServe implements `weather serve`.
+func runServe(cfg Config, args []string) error {
+	return runServer(cfg, args, "serve", serveUsage, (*Server).Handler)
+}
+
+// runServer parses the flags shared by `weather serve` and `weather exporter`,
+// then serves handler until interrupted.
+func runServer(cfg Config, args []string, name string, usage string, handler func(*Server) http.Handler) error {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), usage)
+		flags.PrintDefaults()
+	}
+
+	var flagged []string
+	locations := &stringList{values: &flagged}
+	flags.Var(locations, "location", "Location or saved place name to serve, repeatable")
+	flags.Var(locations, "l", "Location or saved place name to serve (shorthand)")
+	flags.StringVar(&cfg.Serve.Addr, "addr", cfg.Serve.Addr, "Address to listen on")
+	flags.DurationVar(&cfg.Serve.Interval.Duration, "interval", cfg.Serve.Interval.Duration, "How often forecasts are refreshed")
+	flags.StringVar(&cfg.Units, "units", cfg.Units, "Default system of units: auto, us, si, ca or uk")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if cfg.Serve.Interval.Duration < time.Minute {
+		return fmt.Errorf("--interval must be at least 1m, OpenWeatherMap doesn't update any faster")
+	}
+
+	queries, err := serveQueries(cfg, flagged)
+	if err != nil {
+		return err
+	}
+
+	// Every refresh should reach the provider, but still write the cache so
+	// one-shot runs alongside the server can use it.
+	if responseCache != nil {
+		responseCache.Refresh = true
+	}
+
+	services, err := newServices(cfg)
+	if err != nil {
+		return err
+	}
+
+	server := NewServer(services, cfg.Units, queries)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	httpServer := &http.Server{
+		Addr:              cfg.Serve.Addr,
+		Handler:           handler(server),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go server.Run(ctx, cfg.Serve.Interval.Duration)
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		httpServer.Shutdown(shutdownCtx)
+	}()
+
+	fmt.Printf("Serving %s on http://%s\n", strings.Join(slices.Sorted(maps.Keys(queries)), ", "), cfg.Serve.Addr)
+	if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jptoto/weather/internal/fixtures"
+)
+
+func TestServeHandler(t *testing.T) {
+	silence(t)
+	server := fixtures.NewServer(t)
+	services, err := newServices(fixtureConfig(t, server))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewServer(services, "auto", map[string]string{"berwyn": "Berwyn, PA", "nowhere": "Nowhere"})
+	api := httptest.NewServer(s.Handler())
+	defer api.Close()
+
+	get := func(path string) (int, map[string]interface{}, string) {
+		t.Helper()
+
+		resp, err := http.Get(api.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		var body interface{}
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatalf("%s: %s", path, err)
+		}
+		object, _ := body.(map[string]interface{})
+		errorMessage, _ := object["error"].(string)
+		return resp.StatusCode, object, errorMessage
+	}
+
+	// Nothing has been fetched before the first refresh.
+	if status, _, message := get("/locations/berwyn/current"); status != http.StatusServiceUnavailable || message != "no forecast yet" {
+		t.Errorf("before refreshing got %d %q, want 503 no forecast yet", status, message)
+	}
+
+	s.Refresh(context.Background())
+
+	tests := []struct {
+		path        string
+		wantStatus  int
+		wantUnits   string
+		wantMessage string
+	}{
+		{"/locations/berwyn/current", http.StatusOK, "us", ""},
+		{"/locations/Berwyn/daily", http.StatusOK, "us", ""},
+		{"/locations/berwyn/current?units=si", http.StatusOK, "si", ""},
+		{"/current", http.StatusOK, "us", ""},
+		{"/alerts?units=uk", http.StatusOK, "uk", ""},
+		{"/locations/berwyn/current?units=kelvin", http.StatusBadRequest, "", "unknown units \"kelvin\""},
+		{"/locations/home/current", http.StatusNotFound, "", "not serving a location named \"home\""},
+		{"/locations/berwyn/weekly", http.StatusNotFound, "", "unknown endpoint \"weekly\""},
+		{"/weekly", http.StatusNotFound, "", "unknown endpoint \"weekly\""},
+		// the location never resolved, so its error is all there is to serve
+		{"/locations/nowhere/current", http.StatusServiceUnavailable, "", "failed to find any locations matching \"Nowhere\""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			status, body, message := get(test.path)
+			if status != test.wantStatus {
+				t.Errorf("got status %d, want %d", status, test.wantStatus)
+			}
+			if test.wantUnits != "" && body["units"] != test.wantUnits {
+				t.Errorf("got units %v, want %s", body["units"], test.wantUnits)
+			}
+			if !strings.Contains(message, test.wantMessage) {
+				t.Errorf("got error %q, want one containing %q", message, test.wantMessage)
+			}
+		})
+	}
+
+	// The ?units= override converts the data, not just the label.
+	_, us, _ := get("/locations/berwyn/current")
+	_, si, _ := get("/locations/berwyn/current?units=si")
+	usTemp := us["data"].(map[string]interface{})["temp"].(float64)
+	siTemp := si["data"].(map[string]interface{})["temp"].(float64)
+	if usTemp < 70 || usTemp > 80 || siTemp < 20 || siTemp > 25 {
+		t.Errorf("got %v°F and %v°C, want the fixture's 74°F in both units", usTemp, siTemp)
+	}
+
+	resp, err := http.Get(api.URL + "/locations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var locations []struct {
+		Name  string `json:"name"`
+		Error string `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&locations); err != nil {
+		t.Fatal(err)
+	}
+	if len(locations) != 2 || locations[0].Name != "berwyn" || locations[0].Error != "" || locations[1].Name != "nowhere" || locations[1].Error == "" {
+		t.Errorf("got %+v, want berwyn and nowhere with an error", locations)
+	}
+}
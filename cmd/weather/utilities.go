@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/jptoto/weather/geocode"
+	"github.com/jptoto/weather/render"
+)
+
+func colorize(v string) string {
+	return render.Colorizer.Color(v)
+}
+
+// isHelp reports whether arg asks for help, for commands that take a
+// subcommand rather than flags.
+func isHelp(arg string) bool {
+	return arg == "-h" || arg == "-help" || arg == "--help" || arg == "help"
+}
+
+func printError(err error) {
+	fmt.Fprintln(os.Stderr, colorize("[red]"+err.Error()))
+}
+
+// pickLocation lists the candidates on stderr and reads the user's choice from
+// stdin, for --pick.
+func pickLocation(locations geocode.GeoLocations) (geocode.GeoLocation, error) {
+	return pickLocationFrom(locations, os.Stdin, os.Stderr)
+}
+
+func pickLocationFrom(locations geocode.GeoLocations, in io.Reader, out io.Writer) (geolocation geocode.GeoLocation, err error) {
+	fmt.Fprintln(out, colorize("[white]Which location did you mean?"))
+	for i, l := range locations {
+		fmt.Fprintf(out, "%s %s %s\n", colorize(fmt.Sprintf("[cyan]%2d)", i+1)), l.DisplayName,
+			colorize(fmt.Sprintf("[dark_gray](%s/%s, importance %v)", l.Class, l.Type, render.Round(l.Importance, 3))))
+	}
+
+	reader := bufio.NewReader(in)
+	for {
+		fmt.Fprintf(out, "Enter 1-%d: ", len(locations))
+		line, err := reader.ReadString('\n')
+		choice, convErr := strconv.Atoi(strings.TrimSpace(line))
+		if convErr == nil && choice >= 1 && choice <= len(locations) {
+			return locations[choice-1], nil
+		}
+		if err != nil {
+			return geolocation, fmt.Errorf("no location picked")
+		}
+	}
+}
+
+// stringList is a repeatable string flag, e.g. -l for comparing locations or
+// --rule. The first time it's given on the command line it replaces, rather
+// than adds to, the values from the config file.
+type stringList struct {
+	values *[]string
+	set    bool
+}
+
+func (l *stringList) String() string {
+	if l == nil || l.values == nil {
+		return ""
+	}
+
+	return strings.Join(*l.values, "; ")
+}
+
+func (l *stringList) Set(value string) error {
+	if !l.set {
+		*l.values = nil
+		l.set = true
+	}
+	*l.values = append(*l.values, value)
+
+	return nil
+}
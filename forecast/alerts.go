@@ -0,0 +1,89 @@
+package forecast
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Severity ranks alerts. One Call doesn't send a severity, so it's worked out
+// from the event name, which for most national weather services follows the
+// warning > watch > advisory > statement convention.
+type Severity int
+
+const (
+	SeverityUnknown Severity = iota
+	SeverityMinor
+	SeverityModerate
+	SeveritySevere
+	SeverityExtreme
+)
+
+var SeverityNames = []string{"unknown", "minor", "moderate", "severe", "extreme"}
+
+func (s Severity) String() string {
+	if int(s) < len(SeverityNames) {
+		return SeverityNames[s]
+	}
+
+	return "unknown"
+}
+
+// ParseSeverity is the inverse of Severity.String, ignoring case.
+func ParseSeverity(name string) (Severity, error) {
+	for i, severityName := range SeverityNames {
+		if strings.EqualFold(name, severityName) {
+			return Severity(i), nil
+		}
+	}
+
+	return SeverityUnknown, fmt.Errorf("unknown severity %q, must be one of: %s", name, strings.Join(SeverityNames, ", "))
+}
+
+// ClassifyAlert works out an alert's severity from its event name.
+func ClassifyAlert(alert Alerts) Severity {
+	event := strings.ToLower(alert.Event)
+
+	switch {
+	case strings.Contains(event, "emergency") || strings.Contains(event, "extreme") || strings.Contains(event, "tornado warning"):
+		return SeverityExtreme
+	case strings.Contains(event, "warning"):
+		return SeveritySevere
+	case strings.Contains(event, "watch"):
+		return SeverityModerate
+	case strings.Contains(event, "advisory") || strings.Contains(event, "statement") || strings.Contains(event, "outlook"):
+		return SeverityMinor
+	}
+
+	return SeverityUnknown
+}
+
+// FilterAlerts keeps alerts at least minSeverity whose event contains event,
+// ignoring case.
+func FilterAlerts(alerts []Alerts, minSeverity Severity, event string) (filtered []Alerts) {
+	for _, alert := range alerts {
+		if ClassifyAlert(alert) < minSeverity {
+			continue
+		}
+		if event != "" && !strings.Contains(strings.ToLower(alert.Event), strings.ToLower(event)) {
+			continue
+		}
+		filtered = append(filtered, alert)
+	}
+
+	return filtered
+}
+
+// AlertId identifies an alert across polls. Services reissue an alert with the
+// same sender, event and start when they update it.
+func AlertId(alert Alerts) string {
+	return alert.SenderName + "|" + alert.Event + "|" + strconv.FormatInt(alert.Start, 10)
+}
+
+// AlertFingerprint changes whenever an alert's content does.
+func AlertFingerprint(alert Alerts) string {
+	sum := sha256.Sum256([]byte(strconv.FormatInt(alert.End, 10) + "|" + strings.Join(alert.Tags, ",") + "|" + alert.Description))
+	return hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,37 @@
+package forecast
+
+import (
+	"context"
+	"net/url"
+	"strings"
+
+	"github.com/jptoto/weather/httpclient"
+)
+
+// OpenWeatherMap fetches forecasts from the One Call 3.0 API.
+type OpenWeatherMap struct {
+	ApiKey  string
+	BaseUrl string
+}
+
+func NewOpenWeatherMap(apiKey string) *OpenWeatherMap {
+	return &OpenWeatherMap{
+		ApiKey:  apiKey,
+		BaseUrl: "https://api.openweathermap.org",
+	}
+}
+
+func (o *OpenWeatherMap) Fetch(ctx context.Context, lat, lon string, opts Options) (forecast Forecast, err error) {
+	params := url.Values{}
+	params.Set("lat", lat)
+	params.Set("lon", lon)
+	params.Set("appid", o.ApiKey)
+	if len(opts.Exclude) > 0 {
+		params.Set("exclude", strings.Join(opts.Exclude, ","))
+	}
+	uri := strings.TrimSuffix(o.BaseUrl, "/") + "/data/3.0/onecall?" + params.Encode()
+
+	err = httpclient.Default.GetJson(ctx, uri, &forecast)
+
+	return forecast, err
+}
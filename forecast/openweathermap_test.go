@@ -0,0 +1,60 @@
+package forecast
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/jptoto/weather/httpclient"
+	"github.com/jptoto/weather/internal/fixtures"
+)
+
+func TestOpenWeatherMapFetch(t *testing.T) {
+	server := fixtures.NewServer(t)
+	provider, err := GetProvider("openweathermap", ProviderOptions{ApiKey: fixtures.ApiKey, BaseUrl: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	forecast, err := provider.Fetch(context.Background(), "40.0389", "-75.4483", Options{Exclude: []string{"minutely"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if forecast.Timezone != "America/New_York" || len(forecast.Hourly) != 48 || len(forecast.Daily) != 8 {
+		t.Errorf("decoded %q with %d hours and %d days", forecast.Timezone, len(forecast.Hourly), len(forecast.Daily))
+	}
+	if forecast.Currently.Temperature != 296.58 {
+		t.Errorf("current temperature %v, want it left in Kelvin", forecast.Currently.Temperature)
+	}
+	if len(forecast.Alerts) != 1 || forecast.Alerts[0].Tags[0] != "Flood" {
+		t.Errorf("alerts %+v", forecast.Alerts)
+	}
+
+	requests := server.Requests()
+	if len(requests) != 1 || !strings.Contains(requests[0], "exclude=minutely") {
+		t.Errorf("requests %v, want one excluding minutely", requests)
+	}
+}
+
+func TestOpenWeatherMapBadKey(t *testing.T) {
+	server := fixtures.NewServer(t)
+	provider, err := GetProvider("openweathermap", ProviderOptions{ApiKey: "wrong-key", BaseUrl: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = provider.Fetch(context.Background(), "40.0389", "-75.4483", Options{})
+
+	var authErr *httpclient.AuthError
+	if !errors.As(err, &authErr) {
+		t.Fatalf("got %v, want an AuthError", err)
+	}
+	if strings.Contains(err.Error(), "wrong-key") {
+		t.Errorf("error leaks the API key: %s", err)
+	}
+	if !strings.Contains(err.Error(), "Invalid API key") {
+		t.Errorf("error doesn't say why: %s", err)
+	}
+}
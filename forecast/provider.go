@@ -0,0 +1,57 @@
+package forecast
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Options are the knobs a provider may honor when fetching a forecast.
+// Providers are free to ignore ones they don't support. Units is informational
+// only, see the units package.
+type Options struct {
+	Units   string
+	Exclude []string
+}
+
+// A Provider fetches a forecast for a latitude and longitude and normalizes it
+// into our Forecast struct, in standard units, so the output functions never
+// have to care where the data came from.
+type Provider interface {
+	Fetch(ctx context.Context, lat, lon string, opts Options) (Forecast, error)
+}
+
+// ProviderOptions are passed to every provider constructor.
+type ProviderOptions struct {
+	ApiKey  string
+	BaseUrl string
+}
+
+// Providers holds a constructor for every backend selectable with --provider.
+// Register new backends here.
+var Providers = map[string]func(opts ProviderOptions) Provider{
+	"openweathermap": func(opts ProviderOptions) Provider {
+		provider := NewOpenWeatherMap(opts.ApiKey)
+		if opts.BaseUrl != "" {
+			provider.BaseUrl = opts.BaseUrl
+		}
+		return provider
+	},
+}
+
+const DefaultProvider = "openweathermap"
+
+func GetProvider(name string, opts ProviderOptions) (Provider, error) {
+	if name == "" {
+		name = DefaultProvider
+	}
+
+	newProvider, ok := Providers[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown forecast provider %q, must be one of: %s", name, strings.Join(slices.Sorted(maps.Keys(Providers)), ", "))
+	}
+
+	return newProvider(opts), nil
+}
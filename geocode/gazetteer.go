@@ -0,0 +1,485 @@
+package geocode
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// The gazetteer reads the tab separated dumps published by
+// https://download.geonames.org/export/ so the tool can resolve places with no
+// network at all. It looks for these files in its directory:
+//
+//	cities.txt  - any of the citiesNNN.txt or allCountries.txt dumps
+//	zip.txt     - any of the postal code dumps from export/zip
+//	admin1.txt  - admin1CodesASCII.txt, optional, used for nicer display names
+//
+// cities.txt columns (19):
+//
+//	geonameid, name, asciiname, alternatenames, latitude, longitude,
+//	feature class, feature code, country code, cc2, admin1 code, admin2 code,
+//	admin3 code, admin4 code, population, elevation, dem, timezone,
+//	modification date
+//
+// zip.txt columns (12):
+//
+//	country code, postal code, place name, admin name1, admin code1,
+//	admin name2, admin code2, admin name3, admin code3, latitude, longitude,
+//	accuracy
+//
+// admin1.txt columns (4):
+//
+//	code (e.g. US.PA), name, ascii name, geonameid
+
+type gazetteerCity struct {
+	Id          int
+	Name        string
+	Names       []string // normalized name, ascii name and alternate names
+	Latitude    string
+	Longitude   string
+	FeatureCode string
+	CountryCode string
+	Admin1Code  string
+	Population  int64
+}
+
+type gazetteerZip struct {
+	CountryCode string
+	PostalCode  string
+	PlaceName   string
+	AdminName1  string
+	AdminCode1  string
+	Latitude    string
+	Longitude   string
+}
+
+// Gazetteer is an offline Geocoder backed by GeoNames text dumps.
+type Gazetteer struct {
+	Dir string
+
+	once   sync.Once
+	err    error
+	cities []gazetteerCity
+	zips   map[string][]gazetteerZip
+	admin1 map[string]string
+}
+
+func NewGazetteer(dir string) *Gazetteer {
+	if dir == "" {
+		dir = defaultGazetteerDir()
+	}
+
+	return &Gazetteer{Dir: dir}
+}
+
+// defaultGazetteerDir is $WEATHER_GAZETTEER, or weather/gazetteer under the XDG
+// data directory.
+func defaultGazetteerDir() string {
+	if dir := os.Getenv("WEATHER_GAZETTEER"); dir != "" {
+		return dir
+	}
+
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+
+	return filepath.Join(dataHome, "weather", "gazetteer")
+}
+
+// Available reports whether there is a cities or zip table to search.
+func (g *Gazetteer) Available() bool {
+	for _, name := range []string{"cities.txt", "zip.txt"} {
+		if _, err := os.Stat(filepath.Join(g.Dir, name)); err == nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (g *Gazetteer) Search(ctx context.Context, query string) (locations GeoLocations, err error) {
+	g.once.Do(g.load)
+	if g.err != nil {
+		return locations, g.err
+	}
+
+	// "Berwyn, PA, US" -> name "berwyn", qualifiers "pa" and "us"
+	parts := strings.Split(query, ",")
+	name := normalizePlaceName(parts[0])
+	var qualifiers []string
+	for _, part := range parts[1:] {
+		if q := normalizePlaceName(part); q != "" {
+			qualifiers = append(qualifiers, q)
+		}
+	}
+
+	if name == "" {
+		return locations, nil
+	}
+
+	if zips, ok := g.zips[normalizePostalCode(parts[0])]; ok {
+		for _, zip := range zips {
+			if !g.zipMatchesQualifiers(zip, qualifiers) {
+				continue
+			}
+			locations = append(locations, g.zipToGeoLocation(zip))
+		}
+	}
+
+	for _, city := range g.cities {
+		if err := ctx.Err(); err != nil {
+			return locations, err
+		}
+
+		score := matchScore(name, city.Names)
+		if score == 0 || !g.cityMatchesQualifiers(city, qualifiers) {
+			continue
+		}
+
+		locations = append(locations, g.cityToGeoLocation(city, score))
+	}
+
+	return locations, nil
+}
+
+// Reverse finds the nearest city to a latitude and longitude.
+func (g *Gazetteer) Reverse(ctx context.Context, lat, lon string) (geolocation GeoLocation, err error) {
+	g.once.Do(g.load)
+	if g.err != nil {
+		return geolocation, g.err
+	}
+
+	latF, err := strconv.ParseFloat(lat, 64)
+	if err != nil {
+		return geolocation, fmt.Errorf("invalid latitude %q", lat)
+	}
+	lonF, err := strconv.ParseFloat(lon, 64)
+	if err != nil {
+		return geolocation, fmt.Errorf("invalid longitude %q", lon)
+	}
+
+	nearest := -1
+	nearestDistance := math.Inf(1)
+	for i, city := range g.cities {
+		cityLat, _ := strconv.ParseFloat(city.Latitude, 64)
+		cityLon, _ := strconv.ParseFloat(city.Longitude, 64)
+		if distance := haversine(latF, lonF, cityLat, cityLon); distance < nearestDistance {
+			nearest, nearestDistance = i, distance
+		}
+	}
+
+	if nearest == -1 {
+		return geolocation, fmt.Errorf("no cities in the gazetteer to reverse geocode %s,%s", lat, lon)
+	}
+
+	return g.cityToGeoLocation(g.cities[nearest], 1), nil
+}
+
+// haversine is the great circle distance in kilometers between two points.
+func haversine(lat1, lon1, lat2, lon2 float64) float64 {
+	const earthRadius = 6371.0
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+
+	dLat := toRad(lat2 - lat1)
+	dLon := toRad(lon2 - lon1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+
+	return 2 * earthRadius * math.Asin(math.Sqrt(a))
+}
+
+func (g *Gazetteer) load() {
+	g.zips = map[string][]gazetteerZip{}
+	g.admin1 = map[string]string{}
+
+	if !g.Available() {
+		g.err = fmt.Errorf("no gazetteer found in %s, expected cities.txt and/or zip.txt", g.Dir)
+		return
+	}
+
+	if err := readTsv(filepath.Join(g.Dir, "admin1.txt"), 2, func(fields []string) {
+		g.admin1[fields[0]] = fields[1]
+	}); err != nil && !os.IsNotExist(err) {
+		g.err = err
+		return
+	}
+
+	if err := readTsv(filepath.Join(g.Dir, "cities.txt"), 15, func(fields []string) {
+		id, _ := strconv.Atoi(fields[0])
+		population, _ := strconv.ParseInt(fields[14], 10, 64)
+
+		names := []string{normalizePlaceName(fields[1]), normalizePlaceName(fields[2])}
+		for _, alt := range strings.Split(fields[3], ",") {
+			if alt = normalizePlaceName(alt); alt != "" {
+				names = append(names, alt)
+			}
+		}
+
+		g.cities = append(g.cities, gazetteerCity{
+			Id:          id,
+			Name:        fields[1],
+			Names:       names,
+			Latitude:    fields[4],
+			Longitude:   fields[5],
+			FeatureCode: fields[7],
+			CountryCode: fields[8],
+			Admin1Code:  fields[10],
+			Population:  population,
+		})
+	}); err != nil && !os.IsNotExist(err) {
+		g.err = err
+		return
+	}
+
+	if err := readTsv(filepath.Join(g.Dir, "zip.txt"), 11, func(fields []string) {
+		zip := gazetteerZip{
+			CountryCode: fields[0],
+			PostalCode:  fields[1],
+			PlaceName:   fields[2],
+			AdminName1:  fields[3],
+			AdminCode1:  fields[4],
+			Latitude:    fields[9],
+			Longitude:   fields[10],
+		}
+		key := normalizePostalCode(zip.PostalCode)
+		g.zips[key] = append(g.zips[key], zip)
+	}); err != nil && !os.IsNotExist(err) {
+		g.err = err
+	}
+}
+
+// readTsv calls fn for every line of path with at least minFields columns.
+// Short or comment lines are skipped.
+func readTsv(path string, minFields int, fn func(fields []string)) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+		if len(fields) < minFields {
+			continue
+		}
+		fn(fields)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("reading gazetteer file %s failed: %s", path, err.Error())
+	}
+
+	return nil
+}
+
+func (g *Gazetteer) cityMatchesQualifiers(city gazetteerCity, qualifiers []string) bool {
+	region := normalizePlaceName(g.admin1[city.CountryCode+"."+city.Admin1Code])
+
+	for _, q := range qualifiers {
+		if q != strings.ToLower(city.CountryCode) && q != strings.ToLower(city.Admin1Code) && q != region {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (g *Gazetteer) zipMatchesQualifiers(zip gazetteerZip, qualifiers []string) bool {
+	for _, q := range qualifiers {
+		if q != strings.ToLower(zip.CountryCode) && q != strings.ToLower(zip.AdminCode1) && q != normalizePlaceName(zip.AdminName1) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (g *Gazetteer) cityToGeoLocation(city gazetteerCity, score float64) GeoLocation {
+	displayName := []string{city.Name}
+	if region, ok := g.admin1[city.CountryCode+"."+city.Admin1Code]; ok {
+		displayName = append(displayName, region)
+	}
+	displayName = append(displayName, city.CountryCode)
+
+	return GeoLocation{
+		PlaceId:     city.Id,
+		License:     "Data © GeoNames, CC BY 4.0. https://www.geonames.org",
+		Latitude:    city.Latitude,
+		Longitude:   city.Longitude,
+		DisplayName: strings.Join(displayName, ", "),
+		Class:       "place",
+		Type:        strings.ToLower(city.FeatureCode),
+		Importance:  score * populationWeight(city.Population),
+		Address: GeoAddress{
+			City:        city.Name,
+			State:       g.admin1[city.CountryCode+"."+city.Admin1Code],
+			Country:     countryNames[strings.ToUpper(city.CountryCode)],
+			CountryCode: strings.ToLower(city.CountryCode),
+		},
+	}
+}
+
+func (g *Gazetteer) zipToGeoLocation(zip gazetteerZip) GeoLocation {
+	displayName := []string{zip.PlaceName}
+	if zip.AdminName1 != "" {
+		displayName = append(displayName, zip.AdminName1)
+	}
+	displayName = append(displayName, zip.PostalCode, zip.CountryCode)
+
+	// An exact postal code hit beats any fuzzy name match.
+	return GeoLocation{
+		License:     "Data © GeoNames, CC BY 4.0. https://www.geonames.org",
+		Latitude:    zip.Latitude,
+		Longitude:   zip.Longitude,
+		DisplayName: strings.Join(displayName, ", "),
+		Class:       "place",
+		Type:        "postcode",
+		Importance:  1,
+		Address: GeoAddress{
+			City:        zip.PlaceName,
+			State:       zip.AdminName1,
+			Postcode:    zip.PostalCode,
+			Country:     countryNames[strings.ToUpper(zip.CountryCode)],
+			CountryCode: strings.ToLower(zip.CountryCode),
+		},
+	}
+}
+
+// matchScore rates how well query matches any of a place's names, from 0 (no
+// match) to 1 (exact match).
+func matchScore(query string, names []string) (score float64) {
+	for _, name := range names {
+		switch {
+		case name == "":
+			continue
+		case name == query:
+			return 1
+		case len(query) >= 3 && strings.HasPrefix(name, query):
+			score = math.Max(score, 0.7)
+		default:
+			// Only spend time on edit distance when the lengths are close
+			// enough that it could possibly be a typo.
+			if d := len(name) - len(query); d > 2 || d < -2 || name[0] != query[0] {
+				continue
+			}
+			if distance := levenshtein(query, name); distance <= 2 {
+				score = math.Max(score, 0.8-0.2*float64(distance))
+			}
+		}
+	}
+
+	return score
+}
+
+// populationWeight scales a match score so that, like the Importance returned by
+// geocode.maps.co, big places beat small ones with the same name. A village
+// weighs about 0.5 and a city of ten million about 1.
+func populationWeight(population int64) float64 {
+	return 0.5 + 0.5*math.Min(math.Log10(float64(population)+1)/7, 1)
+}
+
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(rb)]
+}
+
+// normalizePlaceName lowercases and drops punctuation so "St. Louis" matches
+// "st louis".
+func normalizePlaceName(name string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range strings.ToLower(strings.TrimSpace(name)) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if space && b.Len() > 0 {
+				b.WriteRune(' ')
+			}
+			space = false
+			b.WriteRune(r)
+		default:
+			space = true
+		}
+	}
+
+	return b.String()
+}
+
+func normalizePostalCode(code string) string {
+	return strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(code), " ", ""))
+}
+
+// FallbackGeocoder tries each geocoder in turn and returns the first non-empty
+// result, so an unreachable service can fall back to the offline gazetteer.
+type FallbackGeocoder []Geocoder
+
+func (f FallbackGeocoder) Search(ctx context.Context, query string) (locations GeoLocations, err error) {
+	var errs []string
+	for _, geocoder := range f {
+		locations, err = geocoder.Search(ctx, query)
+		if err == nil && len(locations) > 0 {
+			return locations, nil
+		}
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+
+	if len(errs) > 0 {
+		return locations, fmt.Errorf("all geocoders failed: %s", strings.Join(errs, "; "))
+	}
+
+	return locations, nil
+}
+
+// Reverse asks each geocoder that supports reverse lookups in turn.
+func (f FallbackGeocoder) Reverse(ctx context.Context, lat, lon string) (geolocation GeoLocation, err error) {
+	err = fmt.Errorf("no geocoder supports reverse lookups")
+	for _, geocoder := range f {
+		reverse, ok := geocoder.(ReverseGeocoder)
+		if !ok {
+			continue
+		}
+		geolocation, err = reverse.Reverse(ctx, lat, lon)
+		if err == nil {
+			return geolocation, nil
+		}
+	}
+
+	return geolocation, err
+}
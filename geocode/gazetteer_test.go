@@ -0,0 +1,174 @@
+package geocode
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"berwyn", "berwyn", 0},
+		{"berwin", "berwyn", 1},
+		{"berwn", "berwyn", 1},
+		{"bewryn", "berwyn", 2},
+		{"paris", "", 5},
+		{"zürich", "zurich", 1},
+	}
+
+	for _, test := range tests {
+		if got := levenshtein(test.a, test.b); got != test.want {
+			t.Errorf("levenshtein(%q, %q) = %d, want %d", test.a, test.b, got, test.want)
+		}
+	}
+}
+
+func TestMatchScore(t *testing.T) {
+	names := []string{"st louis", "saint louis"}
+
+	tests := []struct {
+		query string
+		want  float64
+	}{
+		{"st louis", 1},
+		{"saint louis", 1},
+		{"st lo", 0.7},
+		{"st", 0},
+		{"st lous", 0.6},
+		{"st luis", 0.6},
+		{"st lewis", 0.4},
+		{"chicago", 0},
+	}
+
+	for _, test := range tests {
+		got := matchScore(test.query, names)
+		if diff := got - test.want; diff > 1e-9 || diff < -1e-9 {
+			t.Errorf("matchScore(%q) = %v, want %v", test.query, got, test.want)
+		}
+	}
+}
+
+func TestReadTsv(t *testing.T) {
+	var rows [][]string
+	err := readTsv(filepath.Join("testdata", "gazetteer", "cities.txt"), 15, func(fields []string) {
+		rows = append(rows, fields)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the comment and the short line are skipped
+	if len(rows) != 6 || rows[0][1] != "Berwyn" {
+		t.Errorf("got %d rows starting with %q, want the 6 cities", len(rows), rows[0][1])
+	}
+
+	if err := readTsv(filepath.Join("testdata", "missing.txt"), 1, func([]string) {}); !os.IsNotExist(err) {
+		t.Errorf("got %v, want a not exist error", err)
+	}
+}
+
+func TestGazetteerSearch(t *testing.T) {
+	gazetteer := NewGazetteer(filepath.Join("testdata", "gazetteer"))
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"Berwyn", []string{"Berwyn, Pennsylvania, US", "Berwyn, Illinois, US"}},
+		{"Berwyn, IL", []string{"Berwyn, Illinois, US"}},
+		{"berwyn, pennsylvania, us", []string{"Berwyn, Pennsylvania, US"}},
+		{"London, GB", []string{"London, England, GB"}},
+		{"London, Ontario", []string{"London, Ontario, CA"}},
+		{"Londres", []string{"London, England, GB"}},
+		{"St Louis", []string{"St. Louis, Missouri, US"}},
+		{"Pari", []string{"Paris, Île-de-France, FR"}},
+		{"19312", []string{"Berwyn, Pennsylvania, 19312, US"}},
+		{"sw1a 1aa", []string{"London, England, SW1A 1AA, GB"}},
+		{"Berwyn, TX", nil},
+		{"Nowhere", nil},
+		{"", nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			locations, err := gazetteer.Search(context.Background(), test.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, l := range locations {
+				got = append(got, l.DisplayName)
+			}
+			if strings.Join(got, "; ") != strings.Join(test.want, "; ") {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestGazetteerAddress(t *testing.T) {
+	gazetteer := NewGazetteer(filepath.Join("testdata", "gazetteer"))
+
+	locations, err := gazetteer.Search(context.Background(), "Paris")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := GeoAddress{City: "Paris", State: "Île-de-France", Country: "France", CountryCode: "fr"}
+	if len(locations) != 1 || locations[0].Address != want {
+		t.Fatalf("got %+v, want one location with address %+v", locations, want)
+	}
+
+	// Country names work as filters, not just codes.
+	geolocation, err := LocateWith(context.Background(), gazetteer, nil, "London", LocateOptions{Country: "Canada"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if geolocation.DisplayName != "London, Ontario, CA" {
+		t.Errorf("got %q, want London, Ontario", geolocation.DisplayName)
+	}
+}
+
+func TestGazetteerReverse(t *testing.T) {
+	gazetteer := NewGazetteer(filepath.Join("testdata", "gazetteer"))
+
+	tests := []struct {
+		lat, lon string
+		want     string
+	}{
+		{"40.0389", "-75.4483", "Berwyn, Pennsylvania, US"},
+		{"41.9", "-87.6", "Berwyn, Illinois, US"},
+		{"51.4", "0.2", "London, England, GB"},
+		{"49", "2", "Paris, Île-de-France, FR"},
+	}
+
+	for _, test := range tests {
+		geolocation, err := gazetteer.Reverse(context.Background(), test.lat, test.lon)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if geolocation.DisplayName != test.want {
+			t.Errorf("%s,%s: got %q, want %q", test.lat, test.lon, geolocation.DisplayName, test.want)
+		}
+	}
+
+	if _, err := gazetteer.Reverse(context.Background(), "north", "-75"); err == nil {
+		t.Error("got no error for a latitude that isn't a number")
+	}
+}
+
+func TestGazetteerUnavailable(t *testing.T) {
+	gazetteer := NewGazetteer(t.TempDir())
+	if gazetteer.Available() {
+		t.Error("an empty directory shouldn't be available")
+	}
+	if _, err := gazetteer.Search(context.Background(), "Berwyn"); err == nil || !strings.Contains(err.Error(), "no gazetteer found") {
+		t.Errorf("got %v, want an error saying there's no gazetteer", err)
+	}
+}
@@ -0,0 +1,505 @@
+// Package geocode turns what a user typed, or their ip address, into a
+// latitude and longitude. Geocoder backends are registered in Geocoders, ip
+// locators in IpLocators, and Locate ties them together.
+package geocode
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"math"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jptoto/weather/httpclient"
+)
+
+// response from https://geocode.maps.co
+// comes back in an array ordered by "importance". Probably safe to take the first one sorted by "importance":
+// [
+//   {
+//     "place_id": 318254891,
+//     "licence": "Data © OpenStreetMap contributors, ODbL 1.0. https://osm.org/copyright",
+//     "osm_type": "node",
+//     "osm_id": 158623191,
+//     "boundingbox": [
+//       "40.0248306",
+//       "40.0648306",
+//       "-75.4588053",
+//       "-75.4188053"
+//     ],
+//     "lat": "40.0448306",
+//     "lon": "-75.4388053",
+//     "display_name": "Berwyn, Easttown Township, Chester County, Pennsylvania, 19312, United States",
+//     "class": "place",
+//     "type": "village",
+//     "importance": 0.469575729943735
+//   },
+//   {
+//     "place_id": 318253942,
+//     "licence": "Data © OpenStreetMap contributors, ODbL 1.0. https://osm.org/copyright",
+//     "osm_type": "node",
+//     "osm_id": 2058688121,
+//     "boundingbox": [
+//       "40.0430849",
+//       "40.0530849",
+//       "-75.4475098",
+//       "-75.4375098"
+//     ],
+//     "lat": "40.0480849",
+//     "lon": "-75.4425098",
+//     "display_name": "Berwyn, East Lancaster Avenue, Berwyn, Easttown Township, Chester County, Pennsylvania, 19301, United States",
+//     "class": "railway",
+//     "type": "station",
+//     "importance": 0.2419138440405379
+//   }
+// ]
+
+// Response from ip-api.com
+// {
+//   "status": "success",
+//   "country": "United States",
+//   "countryCode": "US",
+//   "region": "PA",
+//   "regionName": "Pennsylvania",
+//   "city": "Philadelphia",
+//   "zip": "19143",
+//   "lat": 39.9486,
+//   "lon": -75.2339,
+//   "timezone": "America/New_York",
+//   "isp": "Verizon Communications",
+//   "org": "MCI Communications Services, Inc. d/b/a Verizon Business",
+//   "as": "AS701 Verizon Business",
+//   "query": "71.185.185.227"
+// }
+
+type IpLocation struct {
+	Status      string  `json:"status"`
+	Message     string  `json:"message"`
+	Country     string  `json:"country"`
+	CountryCode string  `json:"countryCode"`
+	Region      string  `json:"region"`
+	RegionName  string  `json:"regionName"`
+	City        string  `json:"city"`
+	Zip         string  `json:"zip"`
+	Lat         float64 `json:"lat"`
+	Lon         float64 `json:"lon"`
+	TimeZone    string  `json:"timezone"`
+	ISP         string  `json:"isp"`
+	Org         string  `json:"org"`
+	As          string  `json:"as"`
+	Query       string  `json:"query"`
+}
+
+type GeoLocation struct {
+	PlaceId     int        `json:"place_id"`
+	License     string     `json:"license"`
+	OsmType     string     `json:"osm_type"`
+	OsmId       int        `json:"osm_id"`
+	BoundingBox []string   `json:"boundingbox"`
+	Latitude    string     `json:"lat"`
+	Longitude   string     `json:"lon"`
+	DisplayName string     `json:"display_name"`
+	Class       string     `json:"class"`
+	Type        string     `json:"type"`
+	Importance  float64    `json:"importance"`
+	Address     GeoAddress `json:"address"`
+}
+
+// GeoAddress is the breakdown Nominatim returns with addressdetails=1. Only the
+// parts we use are decoded.
+type GeoAddress struct {
+	City        string `json:"city,omitempty"`
+	Town        string `json:"town,omitempty"`
+	Village     string `json:"village,omitempty"`
+	State       string `json:"state,omitempty"`
+	Postcode    string `json:"postcode,omitempty"`
+	Country     string `json:"country,omitempty"`
+	CountryCode string `json:"country_code,omitempty"`
+}
+
+type GeoLocations []GeoLocation
+
+// A Geocoder turns a free-text query (zip, city, address) into candidate
+// locations. Implementations should return every match they have and leave the
+// ranking to Locate().
+type Geocoder interface {
+	Search(ctx context.Context, query string) (GeoLocations, error)
+}
+
+// A ReverseGeocoder names the place at a latitude and longitude. Geocoders
+// implement it when their service supports it.
+type ReverseGeocoder interface {
+	Reverse(ctx context.Context, lat, lon string) (GeoLocation, error)
+}
+
+// An IpLocator works out where the user is when they didn't give us a location.
+// It returns a query string that a Geocoder can resolve, e.g. a zip code.
+type IpLocator interface {
+	Locate(ctx context.Context) (query string, err error)
+}
+
+// GeocoderOptions are passed to every geocoder constructor. Empty values mean
+// "use the implementation's default".
+type GeocoderOptions struct {
+	BaseUrl      string
+	ApiKey       string
+	GazetteerDir string
+}
+
+// Geocoders holds a constructor for every backend selectable with --geocoder.
+var Geocoders = map[string]func(opts GeocoderOptions) Geocoder{
+	"mapsco": func(opts GeocoderOptions) Geocoder {
+		if opts.BaseUrl == "" {
+			opts.BaseUrl = "https://geocode.maps.co"
+		}
+		return &Nominatim{BaseUrl: opts.BaseUrl, ApiKey: opts.ApiKey}
+	},
+	"nominatim": func(opts GeocoderOptions) Geocoder {
+		if opts.BaseUrl == "" {
+			opts.BaseUrl = "https://nominatim.openstreetmap.org"
+		}
+		return &Nominatim{BaseUrl: opts.BaseUrl, ApiKey: opts.ApiKey}
+	},
+	"gazetteer": func(opts GeocoderOptions) Geocoder {
+		return NewGazetteer(opts.GazetteerDir)
+	},
+}
+
+// IpLocatorOptions are passed to every ip locator constructor. Empty urls mean
+// "use the implementation's default".
+type IpLocatorOptions struct {
+	IpLookupUrl  string
+	GeoLookupUrl string
+}
+
+// IpLocators holds a constructor for every backend selectable with --ip-locator.
+var IpLocators = map[string]func(opts IpLocatorOptions) IpLocator{
+	"ip-api": func(opts IpLocatorOptions) IpLocator {
+		ipApi := NewIpApi()
+		if opts.IpLookupUrl != "" {
+			ipApi.IpLookupUrl = opts.IpLookupUrl
+		}
+		if opts.GeoLookupUrl != "" {
+			ipApi.GeoLookupUrl = opts.GeoLookupUrl
+		}
+		return ipApi
+	},
+}
+
+const (
+	DefaultGeocoder  = "mapsco"
+	DefaultIpLocator = "ip-api"
+)
+
+func GetGeocoder(name string, opts GeocoderOptions) (Geocoder, error) {
+	if name == "" {
+		name = DefaultGeocoder
+	}
+
+	newGeocoder, ok := Geocoders[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown geocoder %q, must be one of: %s", name, strings.Join(slices.Sorted(maps.Keys(Geocoders)), ", "))
+	}
+	geocoder := newGeocoder(opts)
+
+	// Fall back to the offline gazetteer whenever one is installed, so the
+	// tool keeps working without network or a GEOCODING_API_KEY.
+	if gazetteer := NewGazetteer(opts.GazetteerDir); strings.ToLower(name) != "gazetteer" && gazetteer.Available() {
+		return FallbackGeocoder{geocoder, gazetteer}, nil
+	}
+
+	return geocoder, nil
+}
+
+func GetIpLocator(name string, opts IpLocatorOptions) (IpLocator, error) {
+	if name == "" {
+		name = DefaultIpLocator
+	}
+
+	newIpLocator, ok := IpLocators[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown ip locator %q, must be one of: %s", name, strings.Join(slices.Sorted(maps.Keys(IpLocators)), ", "))
+	}
+
+	return newIpLocator(opts), nil
+}
+
+// IpApi finds the user's public ip with icanhazip.com, then looks that ip up
+// with ip-api.com.
+type IpApi struct {
+	IpLookupUrl  string
+	GeoLookupUrl string
+}
+
+func NewIpApi() *IpApi {
+	return &IpApi{
+		IpLookupUrl:  "http://icanhazip.com",
+		GeoLookupUrl: "http://ip-api.com/json/",
+	}
+}
+
+// If the user didn't enter a location, default to finding their current one by
+// first determining their ip address, then doing a geo ip lookup.
+func (i *IpApi) Locate(ctx context.Context) (locationString string, err error) {
+	body, err := httpclient.Default.Get(ctx, i.IpLookupUrl)
+	if err != nil {
+		return locationString, err
+	}
+
+	locationIp := strings.TrimSpace(string(body))
+
+	// Unmarshall the json response body so we can parse out the zip
+	var ipLocation IpLocation
+	if err := httpclient.Default.GetJson(ctx, i.GeoLookupUrl+url.PathEscape(locationIp), &ipLocation); err != nil {
+		return "", err
+	}
+	if ipLocation.Status != "success" {
+		return "", fmt.Errorf("looking up the location of %s failed: %s", locationIp, ipLocation.Message)
+	}
+
+	// Not every ip has a zip, but they all have coordinates.
+	if ipLocation.Zip == "" {
+		return strconv.FormatFloat(ipLocation.Lat, 'f', -1, 64) + "," + strconv.FormatFloat(ipLocation.Lon, 'f', -1, 64), nil
+	}
+
+	return ipLocation.Zip, nil
+}
+
+// Nominatim searches any Nominatim compatible API, which includes
+// https://geocode.maps.co and self-hosted mirrors.
+type Nominatim struct {
+	BaseUrl string
+	ApiKey  string
+}
+
+func (n *Nominatim) Search(ctx context.Context, query string) (locations GeoLocations, err error) {
+	params := url.Values{}
+	params.Set("q", query)
+	params.Set("format", "json")
+	params.Set("addressdetails", "1")
+	if n.ApiKey != "" {
+		params.Set("api_key", n.ApiKey)
+	}
+	uri := strings.TrimSuffix(n.BaseUrl, "/") + "/search?" + params.Encode()
+
+	// Decode the body, we should get back an array of Geolcations to unmarshall
+	err = httpclient.Default.GetJson(ctx, uri, &locations)
+
+	return locations, err
+}
+
+func (n *Nominatim) Reverse(ctx context.Context, lat, lon string) (geolocation GeoLocation, err error) {
+	params := url.Values{}
+	params.Set("lat", lat)
+	params.Set("lon", lon)
+	params.Set("format", "json")
+	params.Set("addressdetails", "1")
+	if n.ApiKey != "" {
+		params.Set("api_key", n.ApiKey)
+	}
+	uri := strings.TrimSuffix(n.BaseUrl, "/") + "/reverse?" + params.Encode()
+
+	// Reverse lookups return a single object, or {"error": "..."} when there
+	// is nothing at those coordinates, e.g. the middle of the ocean.
+	var result struct {
+		GeoLocation
+		Error string `json:"error"`
+	}
+	if err := httpclient.Default.GetJson(ctx, uri, &result); err != nil {
+		return geolocation, err
+	}
+
+	if result.Error != "" {
+		return geolocation, fmt.Errorf("reverse geocoding %s,%s failed: %s", lat, lon, result.Error)
+	}
+
+	return result.GeoLocation, nil
+}
+
+// ParseCoordinates recognizes locations like "40.04,-75.44" so they can skip
+// the geocoding search.
+func ParseCoordinates(location string) (lat, lon string, ok bool) {
+	parts := strings.Split(location, ",")
+	if len(parts) != 2 {
+		return "", "", false
+	}
+
+	lat, lon = strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+	// ParseFloat takes "NaN" and "Inf". Inf is out of range, but NaN
+	// compares false with everything so it needs its own check.
+	latF, err := strconv.ParseFloat(lat, 64)
+	if err != nil || math.IsNaN(latF) || latF < -90 || latF > 90 {
+		return "", "", false
+	}
+	lonF, err := strconv.ParseFloat(lon, 64)
+	if err != nil || math.IsNaN(lonF) || lonF < -180 || lonF > 180 {
+		return "", "", false
+	}
+
+	return lat, lon, true
+}
+
+// locateCoordinates builds a GeoLocation for coordinates the user gave us,
+// borrowing the name of whatever the geocoder finds there. A failed reverse
+// lookup isn't fatal, we just show the coordinates instead.
+func locateCoordinates(ctx context.Context, geocoder Geocoder, lat, lon string) GeoLocation {
+	geolocation := GeoLocation{
+		Latitude:    lat,
+		Longitude:   lon,
+		DisplayName: lat + ", " + lon,
+		Class:       "coordinates",
+	}
+
+	if reverse, ok := geocoder.(ReverseGeocoder); ok {
+		if named, err := reverse.Reverse(ctx, lat, lon); err == nil && named.DisplayName != "" {
+			geolocation.DisplayName = named.DisplayName
+			geolocation.Address = named.Address
+		}
+	}
+
+	return geolocation
+}
+
+// LocateOptions narrow down which of the geocoder's matches Locate picks. The
+// zero value takes the most important match.
+type LocateOptions struct {
+	Country string
+	State   string
+	// MatchIndex picks the nth (1 based) match by importance instead of
+	// the first, for scripts that know "Springfield" #3 is the one they want.
+	MatchIndex int
+	// Pick, when set, is handed the best matches (up to PickLimit, 5 by
+	// default) to choose from whenever there is more than one.
+	Pick      func(locations GeoLocations) (GeoLocation, error)
+	PickLimit int
+}
+
+// Using the location info given by the user, find thier lat and longs with the
+// selected geocoder. If they didn't give us one, ask the ip locator first.
+// Coordinates are used as is.
+func Locate(ctx context.Context, geocoder Geocoder, ipLocator IpLocator, location string) (geolocation GeoLocation, err error) {
+	return LocateWith(ctx, geocoder, ipLocator, location, LocateOptions{})
+}
+
+// LocateWith is Locate, narrowed down by opts.
+func LocateWith(ctx context.Context, geocoder Geocoder, ipLocator IpLocator, location string, opts LocateOptions) (geolocation GeoLocation, err error) {
+	if lat, lon, ok := ParseCoordinates(location); ok {
+		return locateCoordinates(ctx, geocoder, lat, lon), nil
+	}
+
+	if location == "" {
+		location, err = ipLocator.Locate(ctx)
+		if err != nil {
+			return geolocation, err
+		}
+	}
+
+	locations, err := geocoder.Search(ctx, location)
+	if err != nil {
+		return geolocation, err
+	}
+
+	// Sort by the "importance" field in descending order. This should give us the _most_ relevant location
+	sort.SliceStable(locations, func(i, j int) bool {
+		return locations[i].Importance > locations[j].Importance
+	})
+
+	locations = filterLocations(locations, opts.Country, opts.State)
+
+	if len(locations) == 0 {
+		return geolocation, fmt.Errorf("failed to find any locations matching %q", location)
+	}
+
+	switch {
+	case opts.MatchIndex > 0:
+		if opts.MatchIndex > len(locations) {
+			return geolocation, fmt.Errorf("asked for match %d but only found %d locations matching %q", opts.MatchIndex, len(locations), location)
+		}
+		return locations[opts.MatchIndex-1], nil
+	case opts.Pick != nil && len(locations) > 1:
+		limit := opts.PickLimit
+		if limit <= 0 {
+			limit = 5
+		}
+		return opts.Pick(locations[:min(limit, len(locations))])
+	}
+
+	// Take the first, sorted location as it's the cloest match by "importance"
+	return locations[0], nil
+}
+
+// filterLocations keeps the locations in the given country and state. Either can
+// be a name or a code, e.g. "us" or "United States", "PA" or "Pennsylvania".
+// Results cached before we asked for address details only have a DisplayName to
+// go on.
+func filterLocations(locations GeoLocations, country, state string) (filtered GeoLocations) {
+	if country == "" && state == "" {
+		return locations
+	}
+
+	matches := func(want string, values ...string) bool {
+		if want == "" {
+			return true
+		}
+		for _, value := range values {
+			if value != "" && strings.EqualFold(value, want) {
+				return true
+			}
+		}
+		return false
+	}
+
+	// Codes also match by name, display names only have names to go on.
+	countryName := ""
+	if len(country) == 2 {
+		countryName = countryNames[strings.ToUpper(country)]
+	}
+	stateName := ""
+	if len(state) == 2 {
+		stateName = usStateCodes[strings.ToUpper(state)]
+	}
+	matchesCountry := func(values ...string) bool {
+		return matches(country, values...) || (countryName != "" && matches(countryName, values...))
+	}
+	matchesState := func(values ...string) bool {
+		return matches(state, values...) || (stateName != "" && matches(stateName, values...))
+	}
+
+	for _, l := range locations {
+		if l.Address == (GeoAddress{}) {
+			parts := strings.Split(l.DisplayName, ",")
+			for i := range parts {
+				parts[i] = strings.TrimSpace(parts[i])
+			}
+			if matchesCountry(parts...) && matchesState(parts...) {
+				filtered = append(filtered, l)
+			}
+			continue
+		}
+
+		if matchesCountry(l.Address.CountryCode, l.Address.Country) && matchesState(l.Address.State) {
+			filtered = append(filtered, l)
+		}
+	}
+
+	return filtered
+}
+
+// usStateCodes lets --state PA match Nominatim's "Pennsylvania".
+var usStateCodes = map[string]string{
+	"AL": "Alabama", "AK": "Alaska", "AZ": "Arizona", "AR": "Arkansas", "CA": "California",
+	"CO": "Colorado", "CT": "Connecticut", "DE": "Delaware", "DC": "District of Columbia",
+	"FL": "Florida", "GA": "Georgia", "HI": "Hawaii", "ID": "Idaho", "IL": "Illinois",
+	"IN": "Indiana", "IA": "Iowa", "KS": "Kansas", "KY": "Kentucky", "LA": "Louisiana",
+	"ME": "Maine", "MD": "Maryland", "MA": "Massachusetts", "MI": "Michigan", "MN": "Minnesota",
+	"MS": "Mississippi", "MO": "Missouri", "MT": "Montana", "NE": "Nebraska", "NV": "Nevada",
+	"NH": "New Hampshire", "NJ": "New Jersey", "NM": "New Mexico", "NY": "New York",
+	"NC": "North Carolina", "ND": "North Dakota", "OH": "Ohio", "OK": "Oklahoma", "OR": "Oregon",
+	"PA": "Pennsylvania", "RI": "Rhode Island", "SC": "South Carolina", "SD": "South Dakota",
+	"TN": "Tennessee", "TX": "Texas", "UT": "Utah", "VT": "Vermont", "VA": "Virginia",
+	"WA": "Washington", "WV": "West Virginia", "WI": "Wisconsin", "WY": "Wyoming",
+}
@@ -0,0 +1,233 @@
+package geocode
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jptoto/weather/internal/fixtures"
+)
+
+// fixtureServices points the default geocoder and ip locator at a fixture
+// server. The gazetteer directory is empty so an installed one can't answer.
+func fixtureServices(t *testing.T) (*fixtures.Server, Geocoder, IpLocator) {
+	t.Helper()
+
+	server := fixtures.NewServer(t)
+	geocoder, err := GetGeocoder(DefaultGeocoder, GeocoderOptions{BaseUrl: server.URL, GazetteerDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ipLocator, err := GetIpLocator(DefaultIpLocator, IpLocatorOptions{IpLookupUrl: server.IpLookupUrl(), GeoLookupUrl: server.IpGeoUrl()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return server, geocoder, ipLocator
+}
+
+func TestLocate(t *testing.T) {
+	tests := []struct {
+		name     string
+		location string
+		opts     LocateOptions
+		want     string
+	}{
+		{"most important match", "Berwyn", LocateOptions{}, "Berwyn, Tredyffrin Township, Chester County, Pennsylvania, 19312, United States"},
+		{"match index", "Berwyn", LocateOptions{MatchIndex: 2}, "Berwyn, Cook County, Illinois, United States"},
+		{"country filter", "Berwyn", LocateOptions{Country: "ca"}, "Berwyn, Alberta, Canada"},
+		{"state filter", "Berwyn", LocateOptions{State: "IL"}, "Berwyn, Cook County, Illinois, United States"},
+		{"ip location", "", LocateOptions{}, "Berwyn, Tredyffrin Township, Chester County, Pennsylvania, 19312, United States"},
+		{"coordinates", "40.0389,-75.4483", LocateOptions{}, "Berwyn, Tredyffrin Township, Chester County, Pennsylvania, 19312, United States"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, geocoder, ipLocator := fixtureServices(t)
+
+			geolocation, err := LocateWith(context.Background(), geocoder, ipLocator, test.location, test.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if geolocation.DisplayName != test.want {
+				t.Errorf("got %q, want %q", geolocation.DisplayName, test.want)
+			}
+		})
+	}
+}
+
+func TestLocatePick(t *testing.T) {
+	_, geocoder, ipLocator := fixtureServices(t)
+
+	var offered GeoLocations
+	opts := LocateOptions{PickLimit: 2, Pick: func(locations GeoLocations) (GeoLocation, error) {
+		offered = locations
+		return locations[1], nil
+	}}
+
+	geolocation, err := LocateWith(context.Background(), geocoder, ipLocator, "Berwyn", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(offered) != 2 {
+		t.Errorf("offered %d locations, want PickLimit", len(offered))
+	}
+	if want := "Berwyn, Cook County, Illinois, United States"; geolocation.DisplayName != want {
+		t.Errorf("got %q, want %q", geolocation.DisplayName, want)
+	}
+}
+
+func TestLocateByIpAsksForTheZip(t *testing.T) {
+	server, geocoder, ipLocator := fixtureServices(t)
+
+	if _, err := Locate(context.Background(), geocoder, ipLocator, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	requests := strings.Join(server.Requests(), "\n")
+	for _, want := range []string{"/ip", "/json/203.0.113.7", "/search?addressdetails=1&format=json&q=19312"} {
+		if !strings.Contains(requests, want) {
+			t.Errorf("expected a request for %s, got:\n%s", want, requests)
+		}
+	}
+}
+
+func TestIpApiLocate(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		want     string
+		wantErr  string
+	}{
+		{"zip", `{"status": "success", "zip": "19312", "lat": 40.0389, "lon": -75.4483}`, "19312", ""},
+		{"no zip", `{"status": "success", "zip": "", "lat": 40.0389, "lon": -75.4483}`, "40.0389,-75.4483", ""},
+		{"failed", `{"status": "fail", "message": "private range", "query": "10.0.0.1"}`, "", "private range"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/ip" {
+					fmt.Fprintln(w, "10.0.0.1")
+					return
+				}
+				fmt.Fprint(w, test.response)
+			}))
+			defer server.Close()
+
+			ipApi := &IpApi{IpLookupUrl: server.URL + "/ip", GeoLookupUrl: server.URL + "/json/"}
+			got, err := ipApi.Locate(context.Background())
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Errorf("got error %v, want one containing %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestLocateErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		location string
+		opts     LocateOptions
+		want     string
+	}{
+		{"no matches", "Nowhere", LocateOptions{}, `failed to find any locations matching "Nowhere"`},
+		{"match index too big", "Berwyn", LocateOptions{MatchIndex: 9}, "asked for match 9 but only found 3"},
+		{"filtered out", "Berwyn", LocateOptions{Country: "fr"}, "failed to find any locations"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, geocoder, ipLocator := fixtureServices(t)
+
+			_, err := LocateWith(context.Background(), geocoder, ipLocator, test.location, test.opts)
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("got error %v, want one containing %q", err, test.want)
+			}
+		})
+	}
+}
+
+func TestParseCoordinates(t *testing.T) {
+	tests := []struct {
+		location string
+		lat, lon string
+		ok       bool
+	}{
+		{"40.0389,-75.4483", "40.0389", "-75.4483", true},
+		{" 40.0389 , -75.4483 ", "40.0389", "-75.4483", true},
+		{"-90,180", "-90", "180", true},
+		{"90.1,0", "", "", false},
+		{"0,-180.5", "", "", false},
+		{"Berwyn, PA", "", "", false},
+		{"40.0389", "", "", false},
+		{"1,2,3", "", "", false},
+		{"NaN,NaN", "", "", false},
+		{"0,nan", "", "", false},
+		{"Inf,0", "", "", false},
+		{"0,-Inf", "", "", false},
+	}
+
+	for _, test := range tests {
+		lat, lon, ok := ParseCoordinates(test.location)
+		if lat != test.lat || lon != test.lon || ok != test.ok {
+			t.Errorf("ParseCoordinates(%q) = %q, %q, %v, want %q, %q, %v", test.location, lat, lon, ok, test.lat, test.lon, test.ok)
+		}
+	}
+}
+
+func TestFilterLocations(t *testing.T) {
+	locations := GeoLocations{
+		// cached before addresses were asked for
+		{DisplayName: "Berwyn, Easttown Township, Chester County, Pennsylvania, 19312, United States"},
+		{DisplayName: "Berwyn, Alberta, Canada"},
+		{DisplayName: "Berwyn, Cook County, Illinois, United States", Address: GeoAddress{State: "Illinois", Country: "United States", CountryCode: "us"}},
+		{DisplayName: "London, England, GB", Address: GeoAddress{State: "England", Country: "United Kingdom", CountryCode: "gb"}},
+	}
+
+	tests := []struct {
+		country, state string
+		want           []int
+	}{
+		{"", "", []int{0, 1, 2, 3}},
+		{"us", "", []int{0, 2}},
+		{"United States", "", []int{0, 2}},
+		{"CA", "", []int{1}},
+		{"gb", "", []int{3}},
+		{"United Kingdom", "", []int{3}},
+		{"", "PA", []int{0}},
+		{"", "illinois", []int{2}},
+		{"us", "AB", nil},
+		{"fr", "", nil},
+	}
+
+	for _, test := range tests {
+		var want GeoLocations
+		for _, i := range test.want {
+			want = append(want, locations[i])
+		}
+
+		got := filterLocations(locations, test.country, test.state)
+		if len(got) != len(want) {
+			t.Errorf("country %q state %q: got %d locations, want %d", test.country, test.state, len(got), len(want))
+			continue
+		}
+		for i := range got {
+			if got[i].DisplayName != want[i].DisplayName {
+				t.Errorf("country %q state %q: got %q, want %q", test.country, test.state, got[i].DisplayName, want[i].DisplayName)
+			}
+		}
+	}
+}
@@ -2,4 +2,9 @@ module github.com/jptoto/weather
 
 go 1.24.2
 
-require github.com/mitchellh/colorstring v0.0.0-20190213212951-d06e56a500db
+require (
+	github.com/mitchellh/colorstring v0.0.0-20190213212951-d06e56a500db
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require github.com/BurntSushi/toml v1.6.0
@@ -0,0 +1,354 @@
+// Package httpclient is the HTTP layer every service in this module makes
+// requests through. It adds per attempt timeouts, retries with jittered
+// backoff on network errors, 5xx and 429 responses, and typed errors that
+// never include API keys.
+package httpclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Client is the one place every service makes requests through. Each
+// attempt has a timeout, failed attempts on network errors, 5xx and 429
+// responses are retried with jittered exponential backoff, and failures come
+// back as one of the typed errors below.
+//
+// Only idempotent requests are retried after a network error or 5xx, since
+// the server may have acted on them. A 429 was refused outright, so POSTs are
+// retried on those too.
+type Client struct {
+	Client     *http.Client
+	Retries    int
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// MaxRetryAfter is the longest Retry-After we'll wait out. Anything longer
+	// is returned as a QuotaError or StatusError straight away.
+	MaxRetryAfter time.Duration
+}
+
+func New(timeout time.Duration, retries int) *Client {
+	return &Client{
+		Client:        &http.Client{Timeout: timeout},
+		Retries:       retries,
+		MinBackoff:    500 * time.Millisecond,
+		MaxBackoff:    10 * time.Second,
+		MaxRetryAfter: 30 * time.Second,
+	}
+}
+
+// Default is shared by every service. Replace it to change timeouts or retries
+// everywhere at once.
+var Default = New(10*time.Second, 2)
+
+// NetworkError is a request that never got a response: DNS, refused
+// connections, timeouts and the like.
+type NetworkError struct {
+	Url string
+	Err error
+}
+
+func (e *NetworkError) Error() string {
+	return fmt.Sprintf("http request to %s failed: %s", e.Url, e.Err.Error())
+}
+
+func (e *NetworkError) Unwrap() error { return e.Err }
+
+// AuthError is a 401 or 403, almost always a missing or wrong API key.
+type AuthError struct {
+	Url     string
+	Status  string
+	Message string
+}
+
+func (e *AuthError) Error() string {
+	return fmt.Sprintf("%s rejected the request (%s), check the API key%s", e.Url, e.Status, detail(e.Message))
+}
+
+// QuotaError is a 429 that outlasted the retries.
+type QuotaError struct {
+	Url        string
+	RetryAfter time.Duration
+	Message    string
+}
+
+func (e *QuotaError) Error() string {
+	wait := "later"
+	if e.RetryAfter > 0 {
+		wait = "in " + e.RetryAfter.Round(time.Second).String()
+	}
+	return fmt.Sprintf("%s is rate limiting requests, try again %s%s", e.Url, wait, detail(e.Message))
+}
+
+// StatusError is any other unsuccessful response.
+type StatusError struct {
+	Url        string
+	StatusCode int
+	Status     string
+	Message    string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("http request to %s failed: %s%s", e.Url, e.Status, detail(e.Message))
+}
+
+// DecodeError is a successful response whose body wasn't what we expected.
+type DecodeError struct {
+	Url string
+	Err error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("decoding the response from %s failed: %s", e.Url, e.Err.Error())
+}
+
+func (e *DecodeError) Unwrap() error { return e.Err }
+
+func detail(message string) string {
+	if message == "" {
+		return ""
+	}
+	return ": " + message
+}
+
+// secretParams are query parameters holding API keys.
+var secretParams = []string{"appid", "api_key", "apikey", "key", "token"}
+
+// redactUrl hides API keys so urls are safe to put in error messages.
+func redactUrl(u *url.URL) string {
+	redacted := *u
+	query := redacted.Query()
+	for _, param := range secretParams {
+		if query.Has(param) {
+			query.Set(param, "REDACTED")
+		}
+	}
+	redacted.RawQuery = query.Encode()
+
+	return redacted.String()
+}
+
+// redactTarget hides everything but the host of a url we post to, since
+// webhook urls like Slack's carry their secret in the path.
+func redactTarget(u *url.URL) string {
+	redacted := url.URL{Scheme: u.Scheme, Host: u.Host}
+	if u.Path != "" && u.Path != "/" || u.RawQuery != "" {
+		redacted.Path = "/REDACTED"
+	}
+
+	return redacted.String()
+}
+
+// Do sends req, retrying as described on Client, and returns the response
+// of the first successful attempt. The caller closes its body.
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	u := redactUrl(req.URL)
+	if req.Method == http.MethodPost {
+		u = redactTarget(req.URL)
+	}
+	idempotent := idempotentMethods[req.Method]
+
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+
+		resp, err := c.Client.Do(req)
+
+		var wait time.Duration
+		var failure error
+		switch {
+		case err != nil:
+			if ctxErr := req.Context().Err(); ctxErr != nil {
+				return nil, ctxErr
+			}
+			// http.Client errors repeat the url, key and all
+			var urlErr *url.Error
+			if errors.As(err, &urlErr) {
+				err = urlErr.Err
+			}
+			failure = &NetworkError{Url: u, Err: err}
+			if !idempotent {
+				return nil, failure
+			}
+		case resp.StatusCode < 300:
+			return resp, nil
+		default:
+			message := responseMessage(resp)
+			switch {
+			case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+				return nil, &AuthError{Url: u, Status: resp.Status, Message: message}
+			case resp.StatusCode == http.StatusTooManyRequests:
+				wait = retryAfter(resp.Header.Get("Retry-After"))
+				if wait > c.MaxRetryAfter {
+					return nil, &QuotaError{Url: u, RetryAfter: wait, Message: message}
+				}
+				failure = &QuotaError{Url: u, RetryAfter: wait, Message: message}
+			case resp.StatusCode >= 500:
+				wait = retryAfter(resp.Header.Get("Retry-After"))
+				failure = &StatusError{Url: u, StatusCode: resp.StatusCode, Status: resp.Status, Message: message}
+				if !idempotent || wait > c.MaxRetryAfter {
+					return nil, failure
+				}
+			default:
+				return nil, &StatusError{Url: u, StatusCode: resp.StatusCode, Status: resp.Status, Message: message}
+			}
+		}
+
+		if attempt >= c.Retries {
+			return nil, failure
+		}
+
+		if wait == 0 {
+			wait = c.backoff(attempt)
+		}
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// idempotentMethods are safe to send again when we can't tell whether the
+// server acted on the first attempt. An empty method is a GET.
+var idempotentMethods = map[string]bool{
+	"":                 true,
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodOptions: true,
+	http.MethodPut:     true,
+	http.MethodDelete:  true,
+}
+
+// backoff doubles MinBackoff for each attempt, capped at MaxBackoff, then
+// picks a random wait between half and all of that so clients that failed
+// together don't retry together.
+func (c *Client) backoff(attempt int) time.Duration {
+	ceiling := c.MinBackoff << attempt
+	if ceiling <= 0 || ceiling > c.MaxBackoff {
+		ceiling = c.MaxBackoff
+	}
+
+	half := int64(ceiling / 2)
+	if half <= 0 {
+		return ceiling
+	}
+	return time.Duration(half + rand.Int63n(half))
+}
+
+// retryAfter parses a Retry-After header, given either in seconds or as an
+// http date.
+func retryAfter(header string) time.Duration {
+	if header == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(header); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(header); err == nil {
+		return max(time.Until(date), 0)
+	}
+
+	return 0
+}
+
+// responseMessage reads and closes the body of a failed response, pulling out
+// the message APIs tend to put in their error JSON.
+func responseMessage(resp *http.Response) string {
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if err != nil {
+		return ""
+	}
+
+	var body struct {
+		Message string `json:"message"`
+		Error   string `json:"error"`
+	}
+	if json.Unmarshal(b, &body) == nil {
+		if body.Message != "" {
+			return body.Message
+		}
+		return body.Error
+	}
+
+	return ""
+}
+
+// Get fetches uri and returns the response body.
+func (c *Client) Get(ctx context.Context, uri string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return nil, fmt.Errorf("building request failed: %s", err.Error())
+	}
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, &NetworkError{Url: redactUrl(req.URL), Err: err}
+	}
+
+	return b, nil
+}
+
+// GetJson fetches uri and decodes the JSON response into v.
+func (c *Client) GetJson(ctx context.Context, uri string, v interface{}) error {
+	b, err := c.Get(ctx, uri)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(b, v); err != nil {
+		u, _ := url.Parse(uri)
+		return &DecodeError{Url: redactUrl(u), Err: err}
+	}
+
+	return nil
+}
+
+// PostJson sends v as JSON to uri, ignoring the response body.
+func (c *Client) PostJson(ctx context.Context, uri string, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, uri, strings.NewReader(string(b)))
+	if err != nil {
+		// url errors repeat the url, secret and all
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("building request failed: %s", err.Error())
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	return nil
+}
@@ -0,0 +1,130 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClientRetries(t *testing.T) {
+	tests := []struct {
+		name     string
+		post     bool
+		statuses []int
+		wantErr  interface{}
+		wantHits int
+	}{
+		{"recovers from 5xx", false, []int{503, 502, 200}, nil, 3},
+		{"gives up on 5xx", false, []int{500, 500, 500, 500}, &StatusError{}, 3},
+		{"doesn't wait out a long Retry-After on 5xx", false, []int{-503, 200}, &StatusError{}, 1},
+		{"retries 429", false, []int{429, 200}, nil, 2},
+		{"gives up on 429", false, []int{429, 429, 429}, &QuotaError{}, 3},
+		{"doesn't retry 401", false, []int{401, 200}, &AuthError{}, 1},
+		{"doesn't retry 404", false, []int{404, 200}, &StatusError{}, 1},
+		{"bad json", false, []int{-200}, &DecodeError{}, 1},
+		{"doesn't retry a POST after 5xx", true, []int{503, 200}, &StatusError{}, 1},
+		{"retries a POST after 429", true, []int{429, 200}, nil, 2},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			hits := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				status := test.statuses[min(hits, len(test.statuses)-1)]
+				hits++
+				switch {
+				case status == -200:
+					w.Write([]byte(`{"lat":`))
+				case status == 429:
+					w.Header().Set("Retry-After", "0")
+					w.WriteHeader(status)
+				case status == -503:
+					w.Header().Set("Retry-After", "3600")
+					w.WriteHeader(503)
+				default:
+					w.WriteHeader(status)
+					w.Write([]byte(`{"lat": 1}`))
+				}
+			}))
+			defer server.Close()
+
+			client := New(time.Second, 2)
+			client.MinBackoff = time.Millisecond
+			client.MaxBackoff = 10 * time.Millisecond
+
+			var v struct {
+				Lat float64 `json:"lat"`
+			}
+			var err error
+			if test.post {
+				err = client.PostJson(context.Background(), server.URL, v)
+			} else {
+				err = client.GetJson(context.Background(), server.URL, &v)
+			}
+
+			switch want := test.wantErr.(type) {
+			case nil:
+				if err != nil {
+					t.Errorf("got %v, want success", err)
+				}
+			case *StatusError:
+				if !errors.As(err, &want) {
+					t.Errorf("got %v, want a StatusError", err)
+				}
+			case *QuotaError:
+				if !errors.As(err, &want) {
+					t.Errorf("got %v, want a QuotaError", err)
+				}
+			case *AuthError:
+				if !errors.As(err, &want) {
+					t.Errorf("got %v, want an AuthError", err)
+				}
+			case *DecodeError:
+				if !errors.As(err, &want) {
+					t.Errorf("got %v, want a DecodeError", err)
+				}
+			}
+			if hits != test.wantHits {
+				t.Errorf("server was hit %d times, want %d", hits, test.wantHits)
+			}
+		})
+	}
+}
+
+func TestRedactUrl(t *testing.T) {
+	u, _ := url.Parse("https://api.example.com/onecall?lat=1&appid=secret&token=hunter2")
+
+	got := redactUrl(u)
+	if want := "https://api.example.com/onecall?appid=REDACTED&lat=1&token=REDACTED"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestPostErrorsHideWebhookSecret(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	client := New(time.Second, 0)
+	err := client.PostJson(context.Background(), server.URL+"/services/T000/B000/hunter2", map[string]string{"text": "hi"})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if strings.Contains(err.Error(), "hunter2") || strings.Contains(err.Error(), "T000") {
+		t.Errorf("error shows the webhook secret: %s", err)
+	}
+	if !strings.Contains(err.Error(), strings.TrimPrefix(server.URL, "http://")) {
+		t.Errorf("error should still name the host: %s", err)
+	}
+
+	err = client.PostJson(context.Background(), "https://hooks.example.com/hunter2\x7f", nil)
+	if err == nil || strings.Contains(err.Error(), "hunter2") {
+		t.Errorf("error shows the webhook secret: %v", err)
+	}
+}
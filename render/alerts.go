@@ -0,0 +1,44 @@
+package render
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/jptoto/weather/forecast"
+)
+
+// SeverityColors is the colorstring color alerts of each severity are printed in.
+var SeverityColors = map[forecast.Severity]string{
+	forecast.SeverityUnknown:  "white",
+	forecast.SeverityMinor:    "yellow",
+	forecast.SeverityModerate: "light_red",
+	forecast.SeveritySevere:   "red",
+	forecast.SeverityExtreme:  "_red_",
+}
+
+// WriteAlert writes an alert with its times in loc. A non empty status, like
+// "Updated", is put in front of the event name.
+func WriteAlert(w io.Writer, alert forecast.Alerts, loc *time.Location, status string) {
+	severity := forecast.ClassifyAlert(alert)
+	color := SeverityColors[severity]
+
+	title := alert.Event
+	if status != "" {
+		title = status + ": " + title
+	}
+	fmt.Fprintln(w, colorize(fmt.Sprintf("[%s]%s", color, title))+colorize(fmt.Sprintf(" [dark_gray](%s)", severity)))
+
+	if alert.SenderName != "" {
+		fmt.Fprintln(w, colorize("[dark_gray]Issued by "+alert.SenderName))
+	}
+	if len(alert.Tags) > 0 {
+		fmt.Fprintln(w, colorize("[dark_gray]Tags: "+strings.Join(alert.Tags, ", ")))
+	}
+	if alert.Description != "" {
+		fmt.Fprintln(w, colorize("[red]"+strings.TrimRight(alert.Description, "\n")))
+	}
+	fmt.Fprintln(w, "\t\t\t"+colorize("[red]Created: "+forecast.TimeIn(alert.Start, loc).Format("January 2 at 3:04pm MST")))
+	fmt.Fprintln(w, "\t\t\t"+colorize("[red]Expires: "+forecast.TimeIn(alert.End, loc).Format("January 2 at 3:04pm MST"))+"\n")
+}
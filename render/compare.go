@@ -0,0 +1,87 @@
+package render
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+
+	"github.com/jptoto/weather/forecast"
+	"github.com/jptoto/weather/geocode"
+	"github.com/jptoto/weather/units"
+)
+
+// Comparison is one location's slot in a side by side comparison. Err is set
+// instead of Forecast when that location couldn't be resolved or fetched, so one
+// bad location doesn't sink the others.
+type Comparison struct {
+	Query    string
+	Location geocode.GeoLocation
+	Forecast forecast.Forecast
+	Err      error
+}
+
+// WriteComparison writes current conditions with a row per location, then the
+// daily highs and lows with a column per location. Days are matched up by the
+// calendar date at each location, so places in different time zones line up.
+func WriteComparison(w io.Writer, results []Comparison, days int, unitSystem string) {
+	unitsFormat := units.Formats[unitSystem]
+
+	fmt.Fprintln(w, colorize("\n[white]Current Conditions"))
+
+	var rows [][]string
+	for _, result := range results {
+		if result.Err != nil {
+			rows = append(rows, []string{result.Query, colorize("[red]" + result.Err.Error())})
+			continue
+		}
+
+		current := result.Forecast.Currently
+		rows = append(rows, []string{
+			result.Query,
+			fmt.Sprintf("%v%s", Round(current.Temperature, 1), unitsFormat.Degrees),
+			fmt.Sprintf("%v%s", Round(current.FeelsLike, 1), unitsFormat.Degrees),
+			fmt.Sprintf("%v%%", current.Humidity),
+			fmt.Sprintf("%v %s %s", Round(current.WindSpeed, 1), unitsFormat.Speed, getBearingDetails(float64(current.WindDegree))),
+			forecast.FirstInfo(current.Info).Description,
+		})
+	}
+	WriteTable(w, []string{"Location", "Temp", "Feels", "Humidity", "Wind", "Conditions"}, rows)
+
+	if days <= 0 {
+		return
+	}
+
+	// date -> query -> "high / low"
+	highLows := map[string]map[string]string{}
+	header := []string{"Date"}
+	for _, result := range results {
+		if result.Err != nil {
+			continue
+		}
+		header = append(header, result.Query)
+
+		loc := result.Forecast.Location()
+		for _, daily := range result.Forecast.Daily[:min(days, len(result.Forecast.Daily))] {
+			date := forecast.TimeIn(daily.Dt, loc).Format("2006-01-02")
+			if highLows[date] == nil {
+				highLows[date] = map[string]string{}
+			}
+			highLows[date][result.Query] = fmt.Sprintf("%v / %v%s", Round(daily.Temperature.Max, 0), Round(daily.Temperature.Min, 0), unitsFormat.Degrees)
+		}
+	}
+
+	dates := slices.Sorted(maps.Keys(highLows))
+
+	rows = nil
+	for _, date := range dates[:min(days, len(dates))] {
+		row := []string{date}
+		for _, query := range header[1:] {
+			row = append(row, highLows[date][query])
+		}
+		rows = append(rows, row)
+	}
+
+	fmt.Fprintln(w, colorize("\n[white]Daily Highs / Lows"))
+	WriteTable(w, header, rows)
+}
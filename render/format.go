@@ -0,0 +1,293 @@
+package render
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/jptoto/weather/forecast"
+	"github.com/jptoto/weather/geocode"
+)
+
+// Report is everything a single run resolved, in the shape emitted by the
+// machine readable --format modes. Field names follow the json tags of the
+// underlying structs, which mirror the OpenWeatherMap and geocoding APIs, and
+// should be treated as a stable interface.
+type Report struct {
+	Location geocode.GeoLocation        `json:"location"`
+	Units    string                     `json:"units"`
+	Current  forecast.CurrentWeather    `json:"current"`
+	Nowcast  string                     `json:"nowcast,omitempty"`
+	Minutely []forecast.MinutelyWeather `json:"minutely,omitempty"`
+	Hourly   []forecast.HourlyWeather   `json:"hourly,omitempty"`
+	Daily    []forecast.DailyWeather    `json:"daily,omitempty"`
+	Alerts   []forecast.Alerts          `json:"alerts,omitempty"`
+}
+
+const DefaultFormat = "text"
+
+var Formats = []string{"text", "json", "yaml", "csv"}
+
+// ValidFormat returns an error naming the supported formats unless format is one
+// of them.
+func ValidFormat(format string) error {
+	for _, f := range Formats {
+		if f == format {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("unknown format %q, must be one of: %s", format, strings.Join(Formats, ", "))
+}
+
+// NewReport trims a forecast down to what was asked for: the minutely forecast
+// with nowcast, the first hours hours and the first days days.
+func NewReport(f forecast.Forecast, geolocation geocode.GeoLocation, units string, days int, hours int, nowcast bool, ignoreAlerts bool) Report {
+	report := Report{
+		Location: geolocation,
+		Units:    units,
+		Current:  f.Currently,
+	}
+
+	if nowcast {
+		report.Nowcast = NowcastSummary(f.Minutely, forecast.FirstInfo(f.Currently.Info))
+		report.Minutely = f.Minutely
+	}
+
+	if hours > 0 {
+		report.Hourly = f.Hourly[:min(hours, len(f.Hourly))]
+	}
+
+	if days > 0 {
+		report.Daily = f.Daily[:min(days, len(f.Daily))]
+	}
+
+	if !ignoreAlerts {
+		report.Alerts = f.Alerts
+	}
+
+	return report
+}
+
+// WriteReport writes a report as json, yaml or csv.
+func WriteReport(w io.Writer, format string, report Report) error {
+	if format == "csv" {
+		return writeCsv(w, report)
+	}
+
+	return EncodeStructured(w, format, report)
+}
+
+// WriteReports writes several reports at once, as a list for json and yaml or
+// one table with a row per location for csv.
+func WriteReports(w io.Writer, format string, reports []Report) error {
+	if format == "csv" {
+		return writeCsv(w, reports...)
+	}
+
+	return EncodeStructured(w, format, reports)
+}
+
+// EncodeStructured writes v as indented json or yaml, with yaml using the json
+// field names.
+func EncodeStructured(w io.Writer, format string, v interface{}) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case "yaml":
+		// Round trip through json so yaml uses the same field names.
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		var generic interface{}
+		dec := json.NewDecoder(bytes.NewReader(b))
+		dec.UseNumber()
+		if err := dec.Decode(&generic); err != nil {
+			return err
+		}
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		defer enc.Close()
+		return enc.Encode(yamlNumbers(generic))
+	}
+
+	return ValidFormat(format)
+}
+
+// yamlNumbers turns the json.Numbers in decoded json back into ints and floats,
+// so timestamps don't come out as 1.745601315e+09.
+func yamlNumbers(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			v[key] = yamlNumbers(value)
+		}
+	case []interface{}:
+		for i, value := range v {
+			v[i] = yamlNumbers(value)
+		}
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	}
+
+	return v
+}
+
+// CsvHeader is the fixed set of columns written by --format csv. Every row has a
+// "kind" of current, minutely, hourly, daily or alert and leaves columns that
+// don't apply empty.
+var CsvHeader = []string{
+	"kind", "location", "lat", "lon", "units", "dt", "end",
+	"main", "description", "temp", "temp_min", "temp_max", "feels_like",
+	"pressure", "humidity", "dew_point", "uvi", "clouds", "visibility",
+	"wind_speed", "wind_deg", "wind_gust", "pop", "rain", "sender_name", "event",
+	"precipitation",
+}
+
+func writeCsv(w io.Writer, reports ...Report) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(CsvHeader); err != nil {
+		return err
+	}
+
+	for _, report := range reports {
+		if err := writeReportCsv(cw, report); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeReportCsv(cw *csv.Writer, report Report) error {
+	row := func(values map[string]string) error {
+		values["location"] = report.Location.DisplayName
+		values["lat"] = report.Location.Latitude
+		values["lon"] = report.Location.Longitude
+		values["units"] = report.Units
+
+		record := make([]string, len(CsvHeader))
+		for i, column := range CsvHeader {
+			record[i] = values[column]
+		}
+		return cw.Write(record)
+	}
+
+	current := report.Current
+	info := forecast.FirstInfo(current.Info)
+	if err := row(map[string]string{
+		"kind":        "current",
+		"dt":          formatInt(current.Dt),
+		"main":        info.Main,
+		"description": info.Description,
+		"temp":        formatFloat(current.Temperature),
+		"feels_like":  formatFloat(current.FeelsLike),
+		"pressure":    formatFloat(current.Pressure),
+		"humidity":    strconv.Itoa(current.Humidity),
+		"dew_point":   formatFloat(current.DewPoint),
+		"uvi":         formatFloat(current.Uvi),
+		"clouds":      strconv.Itoa(current.Clouds),
+		"visibility":  formatFloat(current.Visibility),
+		"wind_speed":  formatFloat(current.WindSpeed),
+		"wind_deg":    strconv.Itoa(current.WindDegree),
+	}); err != nil {
+		return err
+	}
+
+	for _, minutely := range report.Minutely {
+		if err := row(map[string]string{
+			"kind":          "minutely",
+			"dt":            formatInt(minutely.Dt),
+			"precipitation": formatFloat(minutely.Precipitation),
+		}); err != nil {
+			return err
+		}
+	}
+
+	for _, hourly := range report.Hourly {
+		info := forecast.FirstInfo(hourly.Info)
+		if err := row(map[string]string{
+			"kind":        "hourly",
+			"dt":          formatInt(hourly.Dt),
+			"main":        info.Main,
+			"description": info.Description,
+			"temp":        formatFloat(hourly.Temperature),
+			"feels_like":  formatFloat(hourly.FeelsLike),
+			"pressure":    formatFloat(hourly.Pressure),
+			"humidity":    strconv.Itoa(hourly.Humidity),
+			"dew_point":   formatFloat(hourly.DewPoint),
+			"uvi":         formatFloat(hourly.Uvi),
+			"clouds":      strconv.Itoa(hourly.Clouds),
+			"visibility":  formatFloat(hourly.Visibility),
+			"wind_speed":  formatFloat(hourly.WindSpeed),
+			"wind_deg":    strconv.Itoa(hourly.WindDegree),
+			"wind_gust":   formatFloat(hourly.WindGust),
+			"pop":         formatFloat(hourly.Pop),
+		}); err != nil {
+			return err
+		}
+	}
+
+	for _, daily := range report.Daily {
+		info := forecast.FirstInfo(daily.Info)
+		if err := row(map[string]string{
+			"kind":        "daily",
+			"dt":          formatInt(daily.Dt),
+			"main":        info.Main,
+			"description": info.Description,
+			"temp":        formatFloat(daily.Temperature.Day),
+			"temp_min":    formatFloat(daily.Temperature.Min),
+			"temp_max":    formatFloat(daily.Temperature.Max),
+			"feels_like":  formatFloat(daily.FeelsLike.Day),
+			"pressure":    formatFloat(daily.Pressure),
+			"humidity":    strconv.Itoa(daily.Humidity),
+			"dew_point":   formatFloat(daily.DewPoint),
+			"uvi":         formatFloat(daily.Uvi),
+			"clouds":      strconv.Itoa(daily.Clouds),
+			"wind_speed":  formatFloat(daily.WindSpeed),
+			"wind_deg":    strconv.Itoa(daily.WindDeg),
+			"wind_gust":   formatFloat(daily.WindGust),
+			"pop":         formatFloat(daily.Pop),
+			"rain":        formatFloat(daily.Rain),
+		}); err != nil {
+			return err
+		}
+	}
+
+	for _, alert := range report.Alerts {
+		if err := row(map[string]string{
+			"kind":        "alert",
+			"dt":          formatInt(alert.Start),
+			"end":         formatInt(alert.End),
+			"description": alert.Description,
+			"sender_name": alert.SenderName,
+			"event":       alert.Event,
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func formatInt(i int64) string {
+	return strconv.FormatInt(i, 10)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
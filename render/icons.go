@@ -0,0 +1,115 @@
+package render
+
+import (
+	"embed"
+	"fmt"
+	"strings"
+
+	"github.com/jptoto/weather/forecast"
+)
+
+//go:embed icons/*.txt
+var iconFiles embed.FS
+
+// IconColors is the colorstring color each piece of icon art is printed in.
+// Anything missing is printed in blue.
+var IconColors = map[string]string{
+	"clear-day":           "yellow",
+	"clear-night":         "light_yellow",
+	"partly-cloudy-day":   "yellow",
+	"partly-cloudy-night": "light_yellow",
+	"cloudy":              "white",
+	"fog":                 "light_gray",
+	"snow":                "white",
+	"sleet":               "light_cyan",
+	"wind":                "dark_gray",
+	"thunderstorm":        "dark_gray",
+	"tornado":             "dark_gray",
+}
+
+// iconName maps an OpenWeatherMap condition to a piece of bundled icon art. The
+// condition id is the most specific, see
+// https://openweathermap.org/weather-conditions, and the "d"/"n" suffix of the
+// icon code tells us whether it's day or night.
+func iconName(info forecast.WeatherInfo) string {
+	night := strings.HasSuffix(info.Icon, "n")
+
+	switch {
+	case info.Id >= 200 && info.Id < 300:
+		return "thunderstorm"
+	case info.Id >= 300 && info.Id < 400:
+		return "drizzle"
+	case info.Id == 511:
+		return "sleet"
+	case info.Id >= 500 && info.Id < 600:
+		return "rain"
+	case info.Id >= 611 && info.Id <= 616:
+		return "sleet"
+	case info.Id >= 600 && info.Id < 700:
+		return "snow"
+	case info.Id == 781:
+		return "tornado"
+	case info.Id == 771:
+		return "wind"
+	case info.Id >= 700 && info.Id < 800:
+		return "fog"
+	case info.Id == 800 && night:
+		return "clear-night"
+	case info.Id == 800:
+		return "clear-day"
+	case (info.Id == 801 || info.Id == 802) && night:
+		return "partly-cloudy-night"
+	case info.Id == 801 || info.Id == 802:
+		return "partly-cloudy-day"
+	case info.Id == 803 || info.Id == 804:
+		return "cloudy"
+	}
+
+	// No usable id, fall back to the icon code.
+	switch strings.TrimRight(info.Icon, "dn") {
+	case "01":
+		if night {
+			return "clear-night"
+		}
+		return "clear-day"
+	case "02", "03":
+		if night {
+			return "partly-cloudy-night"
+		}
+		return "partly-cloudy-day"
+	case "04":
+		return "cloudy"
+	case "09":
+		return "drizzle"
+	case "10":
+		return "rain"
+	case "11":
+		return "thunderstorm"
+	case "13":
+		return "snow"
+	case "50":
+		return "fog"
+	}
+
+	return ""
+}
+
+// Icon returns the colored icon art for a condition.
+func Icon(info forecast.WeatherInfo) (iconTxt string, err error) {
+	name := iconName(info)
+	if name == "" {
+		return "", fmt.Errorf("No icon found for %s (%d).", info.Icon, info.Id)
+	}
+
+	out, err := iconFiles.ReadFile("icons/" + name + ".txt")
+	if err != nil {
+		return "", fmt.Errorf("No icon found for %s.", name)
+	}
+
+	color, ok := IconColors[name]
+	if !ok {
+		color = "blue"
+	}
+
+	return colorize("[" + color + "]" + string(out)), nil
+}
@@ -0,0 +1,105 @@
+package render
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/jptoto/weather/forecast"
+)
+
+var sparks = []rune("▁▂▃▄▅▆▇█")
+
+// sparkline draws one bar per minute. Dry minutes get the lowest bar and
+// anything wet at least the second, so light drizzle is never invisible. The
+// scale tops out at the heaviest minute, but never less than 1 mm/h so that a
+// drizzle doesn't look like a downpour.
+func sparkline(minutely []forecast.MinutelyWeather) string {
+	peak := 1.0
+	for _, minute := range minutely {
+		peak = math.Max(peak, minute.Precipitation)
+	}
+
+	var b strings.Builder
+	for _, minute := range minutely {
+		if minute.Precipitation <= 0 {
+			b.WriteRune(sparks[0])
+			continue
+		}
+		level := int(math.Ceil(minute.Precipitation / peak * float64(len(sparks)-1)))
+		b.WriteRune(sparks[max(1, min(level, len(sparks)-1))])
+	}
+
+	return b.String()
+}
+
+// NowcastSummary turns the minutely precipitation into a plain English sentence
+// like "Rain starting in 12 min, lasting ~25 min".
+func NowcastSummary(minutely []forecast.MinutelyWeather, info forecast.WeatherInfo) string {
+	kind := "Rain"
+	if info.Id >= 600 && info.Id < 700 {
+		kind = "Snow"
+	}
+
+	if len(minutely) == 0 {
+		return "No minute by minute forecast is available for this location"
+	}
+
+	wet := func(i int) bool { return minutely[i].Precipitation > 0 }
+
+	start := -1
+	for i := range minutely {
+		if wet(i) {
+			start = i
+			break
+		}
+	}
+
+	if start == -1 {
+		return fmt.Sprintf("No precipitation expected in the next %d min", len(minutely))
+	}
+
+	end := len(minutely)
+	for i := start; i < len(minutely); i++ {
+		if !wet(i) {
+			end = i
+			break
+		}
+	}
+
+	switch {
+	case start == 0 && end == len(minutely):
+		return fmt.Sprintf("%s continuing for at least the next %d min", kind, len(minutely))
+	case start == 0:
+		return fmt.Sprintf("%s stopping in %d min", kind, end)
+	case end == len(minutely):
+		return fmt.Sprintf("%s starting in %d min, lasting at least %d min", kind, start, end-start)
+	}
+
+	return fmt.Sprintf("%s starting in %d min, lasting ~%d min", kind, start, end-start)
+}
+
+// WriteNowcast writes a sparkline of the next hour's precipitation and a summary
+// of it.
+func WriteNowcast(w io.Writer, f forecast.Forecast) {
+	fmt.Fprintln(w, colorize("\n[white]Next Hour"))
+
+	if len(f.Minutely) > 0 {
+		fmt.Fprintln(w, colorize("[blue]"+sparkline(f.Minutely)))
+
+		// Label every 15 minutes underneath the bars.
+		var axis strings.Builder
+		for i := 0; i < len(f.Minutely); i += 15 {
+			label := "now"
+			if i > 0 {
+				label = fmt.Sprintf("+%d", i)
+			}
+			axis.WriteString(fmt.Sprintf("%-15s", label))
+		}
+		fmt.Fprintln(w, colorize("[dark_gray]"+strings.TrimRight(axis.String(), " ")))
+	}
+
+	summary := NowcastSummary(f.Minutely, forecast.FirstInfo(f.Currently.Info))
+	fmt.Fprintln(w, colorize("[cyan]"+summary))
+}
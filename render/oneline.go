@@ -0,0 +1,217 @@
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/jptoto/weather/forecast"
+	"github.com/jptoto/weather/geocode"
+	"github.com/jptoto/weather/units"
+)
+
+// Glyphs map the condition names used for the icon art, e.g.
+// "partly-cloudy-day", to a single character for one line output.
+type Glyphs map[string]string
+
+// GlyphSets are the glyphs --glyphs can pick. nerd needs a Nerd Font
+// (https://www.nerdfonts.com), whose weather icons are in the private use area.
+var GlyphSets = map[string]Glyphs{
+	"unicode": {
+		"clear-day":           "☀️",
+		"clear-night":         "🌙",
+		"partly-cloudy-day":   "⛅",
+		"partly-cloudy-night": "☁️",
+		"cloudy":              "☁️",
+		"fog":                 "🌫️",
+		"drizzle":             "🌦️",
+		"rain":                "🌧️",
+		"sleet":               "🌨️",
+		"snow":                "❄️",
+		"thunderstorm":        "⛈️",
+		"tornado":             "🌪️",
+		"wind":                "🌬️",
+	},
+	"nerd": {
+		"clear-day":           "\ue30d",
+		"clear-night":         "\ue32b",
+		"partly-cloudy-day":   "\ue302",
+		"partly-cloudy-night": "\ue37e",
+		"cloudy":              "\ue312",
+		"fog":                 "\ue313",
+		"drizzle":             "\ue319",
+		"rain":                "\ue318",
+		"sleet":               "\ue3ad",
+		"snow":                "\ue31a",
+		"thunderstorm":        "\ue31d",
+		"tornado":             "\ue351",
+		"wind":                "\ue34b",
+	},
+}
+
+const (
+	DefaultGlyphs       = "unicode"
+	DefaultLineTemplate = "{icon} {temp}{unit}"
+)
+
+// LineFormats are the --format modes that squeeze the forecast onto one line
+// for shell prompts and status bars, as plain text or as the JSON waybar and
+// i3bar read.
+var LineFormats = []string{"oneline", "waybar", "i3bar"}
+
+// LineFields describes every {field} a line template can use.
+var LineFields = map[string]string{
+	"icon":       "condition glyph",
+	"condition":  "condition description, e.g. \"light rain\"",
+	"main":       "condition group, e.g. \"Rain\"",
+	"temp":       "temperature",
+	"feels_like": "apparent temperature",
+	"temp_min":   "today's low",
+	"temp_max":   "today's high",
+	"unit":       "temperature unit, e.g. \"°F\"",
+	"humidity":   "relative humidity in percent",
+	"pressure":   "pressure",
+	"wind":       "wind speed",
+	"wind_unit":  "wind speed unit, e.g. \"mph\"",
+	"wind_dir":   "compass direction the wind blows from, e.g. \"SSE\"",
+	"pop":        "today's chance of precipitation in percent",
+	"uvi":        "UV index",
+	"sunrise":    "today's sunrise in the location's time zone",
+	"sunset":     "today's sunset in the location's time zone",
+	"location":   "full location name",
+	"city":       "first part of the location name",
+	"alerts":     "number of active alerts",
+}
+
+var lineFieldPattern = regexp.MustCompile(`\{([a-z_]+)\}`)
+
+// ValidLineTemplate returns an error naming the known fields unless every
+// {field} in template is one of them.
+func ValidLineTemplate(template string) error {
+	for _, match := range lineFieldPattern.FindAllStringSubmatch(template, -1) {
+		if _, ok := LineFields[match[1]]; !ok {
+			return fmt.Errorf("unknown field {%s} in %q, must be one of: %s", match[1], template, strings.Join(slices.Sorted(maps.Keys(LineFields)), ", "))
+		}
+	}
+
+	return nil
+}
+
+// Line is the current conditions squeezed onto one line, with a longer
+// tooltip for status bars that show one on hover.
+type Line struct {
+	Text      string
+	Tooltip   string
+	Condition string
+	// Severity is that of the worst active alert, only meaningful when
+	// Alerts is more than 0.
+	Severity forecast.Severity
+	Alerts   int
+}
+
+// NewLine fills in template with the current conditions. Glyphs for conditions
+// missing from glyphs are left empty.
+func NewLine(template string, f forecast.Forecast, geolocation geocode.GeoLocation, unitSystem string, glyphs Glyphs) (Line, error) {
+	if err := ValidLineTemplate(template); err != nil {
+		return Line{}, err
+	}
+
+	unitsFormat := units.Formats[unitSystem]
+	loc := f.Location()
+	current := f.Currently
+	info := forecast.FirstInfo(current.Info)
+
+	line := Line{Condition: iconName(info), Alerts: len(f.Alerts)}
+	for _, alert := range f.Alerts {
+		line.Severity = max(line.Severity, forecast.ClassifyAlert(alert))
+	}
+
+	number := func(v float64, prec int) string {
+		return strconv.FormatFloat(Round(v, prec), 'f', -1, 64)
+	}
+
+	fields := map[string]string{
+		"icon":       glyphs[line.Condition],
+		"condition":  info.Description,
+		"main":       info.Main,
+		"temp":       number(current.Temperature, 0),
+		"feels_like": number(current.FeelsLike, 0),
+		"unit":       unitsFormat.Degrees,
+		"humidity":   strconv.Itoa(current.Humidity),
+		"pressure":   number(current.Pressure, 1),
+		"wind":       number(current.WindSpeed, 0),
+		"wind_unit":  unitsFormat.Speed,
+		"wind_dir":   getBearingDetails(float64(current.WindDegree)),
+		"uvi":        number(current.Uvi, 1),
+		"location":   geolocation.DisplayName,
+		"city":       strings.TrimSpace(strings.Split(geolocation.DisplayName, ",")[0]),
+		"alerts":     strconv.Itoa(len(f.Alerts)),
+		"sunrise":    forecast.TimeIn(current.Sunrise, loc).Format("3:04pm"),
+		"sunset":     forecast.TimeIn(current.Sunset, loc).Format("3:04pm"),
+	}
+	if len(f.Daily) > 0 {
+		today := f.Daily[0]
+		fields["temp_min"] = number(today.Temperature.Min, 0)
+		fields["temp_max"] = number(today.Temperature.Max, 0)
+		fields["pop"] = number(today.Pop*100, 0)
+	}
+
+	line.Text = strings.TrimSpace(lineFieldPattern.ReplaceAllStringFunc(template, func(match string) string {
+		return fields[match[1:len(match)-1]]
+	}))
+
+	tooltip := []string{
+		geolocation.DisplayName,
+		fmt.Sprintf("%s, %s%s, feels like %s%s", info.Description, fields["temp"], unitsFormat.Degrees, fields["feels_like"], unitsFormat.Degrees),
+		fmt.Sprintf("Wind %s %s %s, humidity %s%%", fields["wind"], unitsFormat.Speed, fields["wind_dir"], fields["humidity"]),
+	}
+	if len(f.Daily) > 0 {
+		tooltip = append(tooltip, fmt.Sprintf("Today %s / %s%s, %s%% chance of precipitation", fields["temp_max"], fields["temp_min"], unitsFormat.Degrees, fields["pop"]))
+	}
+	for _, alert := range f.Alerts {
+		tooltip = append(tooltip, fmt.Sprintf("%s until %s", alert.Event, forecast.TimeIn(alert.End, loc).Format("Mon 3:04pm")))
+	}
+	line.Tooltip = strings.Join(tooltip, "\n")
+
+	return line, nil
+}
+
+// pangoEscaper escapes text for waybar, which reads it as Pango markup.
+var pangoEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// WriteLine writes a line as plain text for "oneline", or as the JSON a
+// waybar custom module with "return-type": "json" or an i3bar block expects.
+func WriteLine(w io.Writer, format string, line Line) error {
+	switch format {
+	case "oneline":
+		_, err := fmt.Fprintln(w, line.Text)
+		return err
+	case "waybar":
+		// https://github.com/Alexays/Waybar/wiki/Module:-Custom
+		class := []string{line.Condition}
+		if line.Alerts > 0 {
+			class = append(class, "alert", "alert-"+line.Severity.String())
+		}
+		return json.NewEncoder(w).Encode(map[string]interface{}{
+			"text":    pangoEscaper.Replace(line.Text),
+			"alt":     line.Condition,
+			"tooltip": pangoEscaper.Replace(line.Tooltip),
+			"class":   class,
+		})
+	case "i3bar":
+		// https://i3wm.org/docs/i3bar-protocol.html
+		block := map[string]interface{}{
+			"name":      "weather",
+			"full_text": line.Text,
+			"urgent":    line.Alerts > 0 && line.Severity >= forecast.SeveritySevere,
+		}
+		return json.NewEncoder(w).Encode(block)
+	}
+
+	return fmt.Errorf("unknown line format %q, must be one of: %s", format, strings.Join(LineFormats, ", "))
+}
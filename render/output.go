@@ -0,0 +1,247 @@
+// Package render writes forecasts for people, as colored text, and for
+// programs, as json, yaml or csv.
+//
+// The text writers take an io.Writer and color their output with Colorizer;
+// set Colorizer.Disable for plain text.
+package render
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jptoto/weather/forecast"
+	"github.com/jptoto/weather/geocode"
+	"github.com/jptoto/weather/units"
+)
+
+var (
+	Directions []string = []string{
+		"N", "NNE", "NE", "ENE", "E", "ESE", "SE", "SSE", "S", "SSW", "SW", "WSW", "W", "WNW", "NW", "NNW",
+	}
+)
+
+func writeWeather(w io.Writer, weather forecast.CurrentWeather, unitsFormat units.Measures) {
+	if weather.Humidity > 0 {
+		humidity := colorize(fmt.Sprintf("[white]%v%s", weather.Humidity, "%"))
+		if weather.Humidity > 70 {
+			fmt.Fprintf(w, "Ick! The humidity is %s\n", humidity)
+		} else {
+			fmt.Fprintf(w, "The humidity is %s\n", humidity)
+		}
+	}
+
+	// if weather.PrecipIntensity > 0 {
+	// 	precInt := colorize(fmt.Sprintf("[white]%v %s", weather.PrecipIntensity, unitsFormat.Precipitation))
+	// 	fmt.Printf("The precipitation intensity of %s is %s\n", colorize("[white]"+weather.PrecipType), precInt)
+	// }
+
+	// if weather.PrecipProbability > 0 {
+	// 	prec := colorize(fmt.Sprintf("[white]%v%s", weather.PrecipProbability*100, "%"))
+	// 	fmt.Printf("The precipitation probability is %s\n", prec)
+	// }
+
+	// if weather.NearestStormDistance > 0 {
+	// 	dist := colorize(fmt.Sprintf("[white]%v %s %v", weather.NearestStormDistance, unitsFormat.Length, getBearingDetails(weather.NearestStormBearing)))
+	// 	fmt.Printf("The nearest storm is %s away\n", dist)
+	// }
+
+	// if weather.WindSpeed > 0 {
+	// 	wind := colorize(fmt.Sprintf("[white]%v %s %v", weather.WindSpeed, unitsFormat.Speed, getBearingDetails(weather.WindBearing)))
+	// 	fmt.Printf("The wind speed is %s\n", wind)
+	// }
+
+	// if weather.CloudCover > 0 {
+	// 	cloudCover := colorize(fmt.Sprintf("[white]%v%s", weather.CloudCover*100, "%"))
+	// 	fmt.Printf("The cloud coverage is %s\n", cloudCover)
+	// }
+
+	// if weather.Visibility < 10 {
+	// 	visibilty := colorize(fmt.Sprintf("[white]%v %s", weather.Visibility, unitsFormat.Length))
+	// 	fmt.Printf("The visibilty is %s\n", visibilty)
+	// }
+
+	// if weather.Pressure > 0 {
+	// 	pressure := colorize(fmt.Sprintf("[white]%v %s", weather.Pressure, "mbar"))
+	// 	fmt.Printf("The pressure is %s\n", pressure)
+	// }
+}
+
+// WriteCurrent writes the current conditions at a location, with icon art and,
+// unless ignoreAlerts is set, any active alerts.
+func WriteCurrent(w io.Writer, f forecast.Forecast, geolocation geocode.GeoLocation, unitSystem string, ignoreAlerts bool) {
+	unitsFormat := units.Formats[unitSystem]
+	loc := f.Location()
+
+	info := forecast.FirstInfo(f.Currently.Info)
+
+	icon, err := Icon(info)
+	if err != nil {
+		fmt.Fprintln(w, colorize("[red]"+err.Error()))
+	} else {
+		fmt.Fprintln(w, icon)
+	}
+
+	location := colorize("[green]" + geolocation.DisplayName)
+	fmt.Fprintf(w, "\nCurrent weather is %s in %s for %s\n", colorize("[cyan]"+info.Description), location, colorize("[cyan]"+forecast.TimeIn(f.Currently.Dt, loc).Format("January 2 at 3:04pm MST")))
+
+	temp := colorize(fmt.Sprintf("[magenta]%v%s", Round(f.Currently.Temperature, 1), unitsFormat.Degrees))
+	feelslike := colorize(fmt.Sprintf("[magenta]%v%s", Round(f.Currently.FeelsLike, 1), unitsFormat.Degrees))
+	fmt.Fprintf(w, "The temperature is %s, but it feels like %s\n\n", temp, feelslike)
+
+	if !ignoreAlerts {
+		for _, alert := range f.Alerts {
+			WriteAlert(w, alert, loc, "")
+		}
+	}
+
+	writeWeather(w, f.Currently, unitsFormat)
+}
+
+// WriteDaily writes the forecast for the next days days, at most as many as the
+// forecast has.
+func WriteDaily(w io.Writer, f forecast.Forecast, days int, unitSystem string) {
+	unitsFormat := units.Formats[unitSystem]
+	loc := f.Location()
+
+	// One Call only gives us 8 days, don't promise more than we have
+	days = max(0, min(days, len(f.Daily)))
+
+	fmt.Fprintln(w, colorize("\n[white]"+fmt.Sprintf("%v Day Forecast", days)))
+
+	for _, daily := range f.Daily[:days] {
+		date := forecast.TimeIn(daily.Dt, loc).Format("Monday, January 2")
+		fmt.Fprintln(w, colorize("\n[magenta]"+date))
+
+		if daily.Summary != "" {
+			fmt.Fprintln(w, colorize("[cyan]"+daily.Summary))
+		} else if info := forecast.FirstInfo(daily.Info); info.Description != "" {
+			fmt.Fprintln(w, colorize("[cyan]"+info.Description))
+		}
+
+		tempMax := colorize(fmt.Sprintf("[blue]%v%s", Round(daily.Temperature.Max, 1), unitsFormat.Degrees))
+		tempMin := colorize(fmt.Sprintf("[blue]%v%s", Round(daily.Temperature.Min, 1), unitsFormat.Degrees))
+		fmt.Fprintf(w, "The temperature high is %s and low is %s\n", tempMax, tempMin)
+
+		feelsLike := func(label string, temp float64) string {
+			return label + " " + colorize(fmt.Sprintf("[cyan]%v%s", Round(temp, 1), unitsFormat.Degrees))
+		}
+		fmt.Fprintf(w, "It will feel like %s, %s, %s and %s\n",
+			feelsLike("morning", daily.FeelsLike.Morn), feelsLike("day", daily.FeelsLike.Day),
+			feelsLike("evening", daily.FeelsLike.Eve), feelsLike("night", daily.FeelsLike.Night))
+
+		if daily.Pop > 0 {
+			pop := colorize(fmt.Sprintf("[white]%v%s", Round(daily.Pop*100, 0), "%"))
+			if daily.Rain > 0 {
+				rain := colorize(fmt.Sprintf("[white]%v %s", Round(daily.Rain, 2), unitsFormat.Rainfall))
+				fmt.Fprintf(w, "The chance of precipitation is %s with %s of rain\n", pop, rain)
+			} else {
+				fmt.Fprintf(w, "The chance of precipitation is %s\n", pop)
+			}
+		}
+
+		if daily.WindSpeed > 0 {
+			wind := colorize(fmt.Sprintf("[white]%v %s %v", Round(daily.WindSpeed, 1), unitsFormat.Speed, getBearingDetails(float64(daily.WindDeg))))
+			if daily.WindGust > 0 {
+				gust := colorize(fmt.Sprintf("[white]%v %s", Round(daily.WindGust, 1), unitsFormat.Speed))
+				fmt.Fprintf(w, "The wind speed is %s with gusts up to %s\n", wind, gust)
+			} else {
+				fmt.Fprintf(w, "The wind speed is %s\n", wind)
+			}
+		}
+
+		if daily.Uvi > 0 {
+			fmt.Fprintf(w, "The UV index is %s\n", colorize(fmt.Sprintf("[white]%v", Round(daily.Uvi, 1))))
+		}
+
+		sunrise := colorize("[yellow]" + forecast.TimeIn(daily.Sunrise, loc).Format("3:04pm"))
+		sunset := colorize("[yellow]" + forecast.TimeIn(daily.Sunset, loc).Format("3:04pm"))
+		fmt.Fprintf(w, "Sunrise is at %s and sunset is at %s\n", sunrise, sunset)
+
+		fmt.Fprintf(w, "The moon is %s\n", colorize("[light_yellow]"+moonPhaseName(daily.MoonPhase)))
+	}
+}
+
+// WriteHourly writes the forecast for the next hours hours, one line each, with
+// more columns when verbose is set.
+func WriteHourly(w io.Writer, f forecast.Forecast, hours int, verbose bool, unitSystem string) {
+	unitsFormat := units.Formats[unitSystem]
+	loc := f.Location()
+
+	hours = max(0, min(hours, len(f.Hourly)))
+
+	fmt.Fprintln(w, colorize("\n[white]"+fmt.Sprintf("%v Hour Forecast", hours)))
+
+	if verbose {
+		fmt.Fprintln(w, colorize(fmt.Sprintf("[white]%-12s %-10s %-10s %-5s %-5s %-9s %-20s %-6s %-5s %s",
+			"Time", "Temp", "Feels", "POP", "Hum", "Dew", "Wind", "Clouds", "UVI", "Conditions")))
+	}
+
+	for index, hourly := range f.Hourly {
+		// only do the amount of hours they request
+		if index == hours {
+			break
+		}
+
+		hour := forecast.TimeIn(hourly.Dt, loc)
+		temp := fmt.Sprintf("%v%s", Round(hourly.Temperature, 1), unitsFormat.Degrees)
+		feelsLike := fmt.Sprintf("%v%s", Round(hourly.FeelsLike, 1), unitsFormat.Degrees)
+		pop := fmt.Sprintf("%v%%", Round(hourly.Pop*100, 0))
+		wind := fmt.Sprintf("%v %s %s", Round(hourly.WindSpeed, 1), unitsFormat.Speed, getBearingDetails(float64(hourly.WindDegree)))
+		clouds := fmt.Sprintf("%v%%", hourly.Clouds)
+
+		if !verbose {
+			fmt.Fprintln(w, colorize(fmt.Sprintf("[cyan]%-5s [magenta]%-8s [dark_gray]%-10s [blue]%-4s [white]%-16s %s",
+				hour.Format("3pm"), temp, "("+feelsLike+")", pop, wind, clouds)))
+			continue
+		}
+
+		if hourly.WindGust > 0 {
+			wind += fmt.Sprintf(" (%v)", Round(hourly.WindGust, 1))
+		}
+		humidity := fmt.Sprintf("%v%%", hourly.Humidity)
+		dewPoint := fmt.Sprintf("%v%s", Round(hourly.DewPoint, 1), unitsFormat.Degrees)
+		fmt.Fprintln(w, colorize(fmt.Sprintf("[cyan]%-12s [magenta]%-10s %-10s [blue]%-5s %-5s %-9s [white]%-20s %-6s %-5v %s",
+			hour.Format("Mon 3:04pm"), temp, feelsLike, pop, humidity, dewPoint, wind, clouds, Round(hourly.Uvi, 1), forecast.FirstInfo(hourly.Info).Description)))
+	}
+}
+
+// WriteTable lines up rows under a header. Widths are counted in runes, and
+// colors are added after padding, so "°" and escape codes don't throw the
+// columns off.
+func WriteTable(w io.Writer, header []string, rows [][]string) {
+	widths := make([]int, len(header))
+	for _, row := range append([][]string{header}, rows...) {
+		for i, cell := range row {
+			// A short row's last cell spills over the columns it doesn't
+			// have, e.g. an error message, so it doesn't count.
+			if i < len(widths) && (i < len(row)-1 || len(row) == len(header)) {
+				widths[i] = max(widths[i], utf8.RuneCountInString(cell))
+			}
+		}
+	}
+
+	pad := func(cell string, width int) string {
+		return cell + strings.Repeat(" ", max(0, width-utf8.RuneCountInString(cell)))
+	}
+
+	var line []string
+	for i, cell := range header {
+		line = append(line, pad(cell, widths[i]))
+	}
+	fmt.Fprintln(w, colorize("[white]"+strings.TrimRight(strings.Join(line, "  "), " ")))
+
+	for _, row := range rows {
+		line = line[:0]
+		for i, cell := range row {
+			if i == 0 {
+				cell = colorize("[cyan]" + pad(cell, widths[i]))
+			} else if i < len(row)-1 {
+				cell = pad(cell, widths[i])
+			}
+			line = append(line, cell)
+		}
+		fmt.Fprintln(w, strings.Join(line, "  "))
+	}
+}
@@ -0,0 +1,186 @@
+package render
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jptoto/weather"
+	"github.com/jptoto/weather/forecast"
+	"github.com/jptoto/weather/geocode"
+	"github.com/jptoto/weather/internal/fixtures"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata/golden")
+
+func TestMain(m *testing.M) {
+	// Golden output mustn't depend on the terminal or the machine's time zone.
+	Colorizer.Disable = true
+	time.Local = time.UTC
+
+	os.Exit(m.Run())
+}
+
+// checkGolden compares got with testdata/golden/name, or rewrites the file
+// when the tests are run with -update.
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+
+	path := filepath.Join("testdata", "golden", name)
+	if *update {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading golden file: %s, run go test -update to create it", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("output doesn't match %s, run go test -update if the change is intended\n--- got\n%s\n--- want\n%s", path, got, want)
+	}
+}
+
+// lookup gets the recorded forecast for location from a fixture server, the
+// same way the command does.
+func lookup(t *testing.T, location string, units string) (weather.Result, error) {
+	t.Helper()
+
+	server := fixtures.NewServer(t)
+	services, err := weather.NewServices(weather.Options{
+		ProviderOptions:  forecast.ProviderOptions{ApiKey: fixtures.ApiKey, BaseUrl: server.URL},
+		GeocoderOptions:  geocode.GeocoderOptions{BaseUrl: server.URL, GazetteerDir: t.TempDir()},
+		IpLocatorOptions: geocode.IpLocatorOptions{IpLookupUrl: server.IpLookupUrl(), GeoLookupUrl: server.IpGeoUrl()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return services.Lookup(context.Background(), location, weather.LookupOptions{Units: units})
+}
+
+func fixtureForecast(t *testing.T, units string) weather.Result {
+	t.Helper()
+
+	result, err := lookup(t, "Berwyn, PA", units)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return result
+}
+
+func TestRenderText(t *testing.T) {
+	for _, units := range []string{"auto", "si"} {
+		t.Run(units, func(t *testing.T) {
+			result := fixtureForecast(t, units)
+
+			var buf bytes.Buffer
+			WriteCurrent(&buf, result.Forecast, result.Location, result.Units, false)
+			WriteNowcast(&buf, result.Forecast)
+			WriteHourly(&buf, result.Forecast, 6, true, result.Units)
+			WriteDaily(&buf, result.Forecast, 5, result.Units)
+
+			checkGolden(t, "text_"+units+".txt", buf.Bytes())
+		})
+	}
+}
+
+func TestRenderFormats(t *testing.T) {
+	result := fixtureForecast(t, "auto")
+	report := NewReport(result.Forecast, result.Location, result.Units, 3, 3, false, false)
+
+	for _, format := range []string{"json", "yaml", "csv"} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WriteReport(&buf, format, report); err != nil {
+				t.Fatal(err)
+			}
+
+			checkGolden(t, "report."+format, buf.Bytes())
+
+			// timestamps are integers, not 1.745601315e+09
+			if format != "csv" && !bytes.Contains(buf.Bytes(), []byte(`1745601315`)) {
+				t.Errorf("current dt isn't written as an integer:\n%s", buf.Bytes())
+			}
+		})
+	}
+}
+
+func TestRenderComparison(t *testing.T) {
+	var results []Comparison
+	for _, query := range []string{"Berwyn, PA", "Nowhere"} {
+		result, err := lookup(t, query, "us")
+		results = append(results, Comparison{Query: query, Location: result.Location, Forecast: result.Forecast, Err: err})
+	}
+
+	var buf bytes.Buffer
+	WriteComparison(&buf, results, 3, "us")
+
+	checkGolden(t, "comparison.txt", buf.Bytes())
+}
+
+func TestRenderLine(t *testing.T) {
+	result := fixtureForecast(t, "auto")
+	template := "{icon} {temp}{unit} {condition}, {wind} {wind_unit} {wind_dir}, {temp_max}/{temp_min} {city}"
+	line, err := NewLine(template, result.Forecast, result.Location, result.Units, GlyphSets["unicode"])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, format := range LineFormats {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WriteLine(&buf, format, line); err != nil {
+				t.Fatal(err)
+			}
+
+			checkGolden(t, "line."+format, buf.Bytes())
+		})
+	}
+}
+
+func TestValidLineTemplate(t *testing.T) {
+	if err := ValidLineTemplate(DefaultLineTemplate); err != nil {
+		t.Errorf("default template: %s", err)
+	}
+	if err := ValidLineTemplate("{temp} {nope}"); err == nil {
+		t.Error("got no error for an unknown field")
+	}
+}
+
+func TestRenderTemplate(t *testing.T) {
+	tmpl, err := ParseTemplate(`{{.Location.DisplayName}} at {{time .Current.Dt "Jan 2 3:04pm MST"}}
+{{(info .Current.Info).Description}}, {{round .Current.Temperature}}{{.Labels.Degrees}}, feels like {{round .Current.FeelsLike 1}}{{.Labels.Degrees}}
+Wind {{round .Current.WindSpeed}} {{.Labels.Speed}} {{bearing .Current.WindDegree}}, {{color "red" "colored"}}
+{{range .Daily}}{{time .Dt "Mon"}} {{round .Temperature.Max}}/{{round .Temperature.Min}}
+{{end}}{{range .Alerts}}{{.Event}}
+{{end}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := fixtureForecast(t, "auto")
+	data := NewTemplateData(result.Forecast, result.Location, result.Units, 3, 0, false, false)
+
+	var buf bytes.Buffer
+	if err := WriteTemplate(&buf, tmpl, data); err != nil {
+		t.Fatal(err)
+	}
+
+	checkGolden(t, "template.txt", buf.Bytes())
+
+	if _, err := ParseTemplate("{{nope .Current}}"); err == nil {
+		t.Error("got no error for an unknown function")
+	}
+	tmpl, _ = ParseTemplate("{{bearing .Location.DisplayName}}")
+	if err := WriteTemplate(&buf, tmpl, data); err == nil {
+		t.Error("got no error for a bearing that isn't a number")
+	}
+}
@@ -0,0 +1,114 @@
+package render
+
+import (
+	"fmt"
+	"io"
+	"text/template"
+	"time"
+
+	"github.com/jptoto/weather/forecast"
+	"github.com/jptoto/weather/geocode"
+	"github.com/jptoto/weather/units"
+)
+
+// TemplateData is what a --template is executed with. It is a Report, so the
+// sections are trimmed the same way, plus the labels for the unit system:
+//
+//	.Location   geocode.GeoLocation, e.g. {{.Location.DisplayName}}
+//	.Units      the unit system, e.g. "us"
+//	.Labels     units.Measures, e.g. {{.Labels.Degrees}} or {{.Labels.Speed}}
+//	.Current    forecast.CurrentWeather, e.g. {{.Current.Temperature}}
+//	.Nowcast    the nowcast summary, with --nowcast
+//	.Minutely   []forecast.MinutelyWeather, with --nowcast
+//	.Hourly     []forecast.HourlyWeather, the first --hours hours
+//	.Daily      []forecast.DailyWeather, the first --days days
+//	.Alerts     []forecast.Alerts, unless --ignore-alerts
+//
+// Values are already converted to the unit system and timestamps are unix
+// seconds, see TemplateFuncs for formatting them.
+type TemplateData struct {
+	Report
+	Labels units.Measures
+
+	loc *time.Location
+}
+
+// NewTemplateData trims a forecast like NewReport and adds the unit labels.
+func NewTemplateData(f forecast.Forecast, geolocation geocode.GeoLocation, unitSystem string, days int, hours int, nowcast bool, ignoreAlerts bool) TemplateData {
+	return TemplateData{
+		Report: NewReport(f, geolocation, unitSystem, days, hours, nowcast, ignoreAlerts),
+		Labels: units.Formats[unitSystem],
+		loc:    f.Location(),
+	}
+}
+
+// TemplateFuncs describes the functions templates can call, on top of the
+// text/template builtins.
+var TemplateFuncs = map[string]string{
+	"bearing": "compass direction for degrees, e.g. {{bearing 157}} is \"SSE\"",
+	"time":    "unix seconds as a Go time layout in the location's time zone, e.g. {{time .Current.Dt \"Mon 3:04pm\"}}",
+	"round":   "round halves away from zero to a number of decimals, none when left out, e.g. {{round .Current.Pressure 1}}",
+	"color":   "color text unless color is off, e.g. {{color \"red\" .Current.Temperature}}",
+	"info":    "the first condition of a weather entry, e.g. {{(info .Current.Info).Description}}",
+}
+
+func templateFuncs(loc *time.Location) template.FuncMap {
+	return template.FuncMap{
+		"bearing": func(degrees interface{}) (string, error) {
+			d, err := toFloat(degrees)
+			return getBearingDetails(d), err
+		},
+		"time": func(seconds int64, layout string) string {
+			return forecast.TimeIn(seconds, loc).Format(layout)
+		},
+		"round": func(x interface{}, prec ...int) (float64, error) {
+			f, err := toFloat(x)
+			if len(prec) > 0 {
+				return Round(f, prec[0]), err
+			}
+			return Round(f, 0), err
+		},
+		"color": func(color string, v interface{}) string {
+			return colorize(fmt.Sprintf("[%s]%v", color, v))
+		},
+		"info": forecast.FirstInfo,
+	}
+}
+
+func toFloat(v interface{}) (float64, error) {
+	switch n := v.(type) {
+	case float64:
+		return n, nil
+	case int:
+		return float64(n), nil
+	case int64:
+		return float64(n), nil
+	}
+
+	return 0, fmt.Errorf("expected a number, got %T", v)
+}
+
+// ParseTemplate parses text with the TemplateFuncs, so mistakes turn up before
+// any requests are made.
+func ParseTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("weather").Funcs(templateFuncs(time.UTC)).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("parsing the template failed: %s", err.Error())
+	}
+
+	return tmpl, nil
+}
+
+// WriteTemplate executes tmpl with data, with times in the forecast's time zone.
+func WriteTemplate(w io.Writer, tmpl *template.Template, data TemplateData) error {
+	loc := data.loc
+	if loc == nil {
+		loc = time.Local
+	}
+
+	if err := tmpl.Funcs(templateFuncs(loc)).Execute(w, data); err != nil {
+		return fmt.Errorf("running the template failed: %s", err.Error())
+	}
+
+	return nil
+}
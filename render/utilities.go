@@ -0,0 +1,64 @@
+package render
+
+import (
+	"math"
+
+	"github.com/mitchellh/colorstring"
+)
+
+// Colorizer is what colorize uses to turn "[red]text" into ANSI escapes. Setting
+// Disable strips the color codes instead, see the color config option.
+var Colorizer = colorstring.Colorize{
+	Colors: colorstring.DefaultColors,
+	Reset:  true,
+}
+
+func colorize(v string) string {
+	return Colorizer.Color(v)
+}
+
+// moonPhaseName describes One Call's moon_phase, where 0 and 1 are a new moon,
+// 0.25 the first quarter, 0.5 a full moon and 0.75 the last quarter.
+func moonPhaseName(phase float64) string {
+	switch {
+	case phase <= 0.02 || phase >= 0.98:
+		return "new"
+	case phase < 0.23:
+		return "a waxing crescent"
+	case phase <= 0.27:
+		return "at first quarter"
+	case phase < 0.48:
+		return "a waxing gibbous"
+	case phase <= 0.52:
+		return "full"
+	case phase < 0.73:
+		return "a waning gibbous"
+	case phase <= 0.77:
+		return "at last quarter"
+	}
+
+	return "a waning crescent"
+}
+
+func getBearingDetails(degrees float64) (direction string) {
+	windDeg := (degrees + 11.25) / 22.5
+	directionInt := int(math.Floor(windDeg)) % 16
+
+	if len(Directions) > directionInt && directionInt >= 0 {
+		direction = Directions[directionInt]
+	}
+
+	return direction
+}
+
+// Round rounds x to prec decimal places, halves away from zero.
+func Round(x float64, prec int) float64 {
+	pow := math.Pow(10, float64(prec))
+	rounded := math.Round(x*pow) / pow
+	if rounded == 0 {
+		// Don't print -0° for temperatures just below freezing.
+		return 0
+	}
+
+	return rounded
+}
@@ -0,0 +1,52 @@
+package render
+
+import (
+	"math"
+	"testing"
+)
+
+func TestBearingDetails(t *testing.T) {
+	tests := []struct {
+		degrees float64
+		want    string
+	}{
+		{0, "N"},
+		{11, "N"},
+		{12, "NNE"},
+		{90, "E"},
+		{180, "S"},
+		{207, "SSW"},
+		{270, "W"},
+		{288, "WNW"},
+		{359, "N"},
+	}
+
+	for _, test := range tests {
+		if got := getBearingDetails(test.degrees); got != test.want {
+			t.Errorf("getBearingDetails(%v) = %q, want %q", test.degrees, got, test.want)
+		}
+	}
+}
+
+func TestRound(t *testing.T) {
+	tests := []struct {
+		x    float64
+		prec int
+		want float64
+	}{
+		{3.2, 0, 3},
+		{3.5, 0, 4},
+		{-3.2, 0, -3},
+		{-3.5, 0, -4},
+		{-3.21, 1, -3.2},
+		{-0.4, 0, 0},
+		{1012.99, 1, 1013},
+		{74.24, 1, 74.2},
+	}
+
+	for _, test := range tests {
+		if got := Round(test.x, test.prec); got != test.want || got == 0 && math.Signbit(got) {
+			t.Errorf("Round(%v, %d) = %v, want %v", test.x, test.prec, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+// Package units converts forecasts from the standard units providers return
+// into the us, si, ca and uk unit systems, and labels the results.
+//
+// Providers always hand back standard units: Kelvin, meters per second, meters
+// of visibility, hPa and millimeters of precipitation. Convert turns those into
+// whichever unit system the user asked for, so every provider gets every unit
+// system for free.
+package units
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/jptoto/weather/forecast"
+)
+
+// Measures are the labels for each kind of measurement in a unit system.
+type Measures struct {
+	Degrees       string
+	Speed         string
+	Length        string
+	Pressure      string
+	Precipitation string
+	Rainfall      string
+}
+
+var (
+	Formats map[string]Measures = map[string]Measures{
+		"us": Measures{
+			Degrees:       "°F",
+			Speed:         "mph",
+			Length:        "miles",
+			Pressure:      "inHg",
+			Precipitation: "in/hr",
+			Rainfall:      "in",
+		},
+		"si": Measures{
+			Degrees:       "°C",
+			Speed:         "m/s",
+			Length:        "kilometers",
+			Pressure:      "hPa",
+			Precipitation: "mm/h",
+			Rainfall:      "mm",
+		},
+		"ca": Measures{
+			Degrees:       "°C",
+			Speed:         "km/h",
+			Length:        "kilometers",
+			Pressure:      "hPa",
+			Precipitation: "mm/h",
+			Rainfall:      "mm",
+		},
+		"uk": Measures{
+			Degrees:       "°C",
+			Speed:         "mph",
+			Length:        "kilometers",
+			Pressure:      "hPa",
+			Precipitation: "mm/h",
+			Rainfall:      "mm",
+		},
+	}
+
+	// CountryUnits is the unit system "auto" picks for a country code. Any
+	// country not listed uses si.
+	CountryUnits = map[string]string{
+		"us": "us",
+		"lr": "us",
+		"mm": "us",
+		"gb": "uk",
+		"ca": "ca",
+	}
+)
+
+const Default = "auto"
+
+// Resolve validates a unit system name and turns "auto" into a concrete system
+// based on the country code of the location.
+func Resolve(units string, countryCode string) (string, error) {
+	units = strings.ToLower(units)
+
+	if units == "" || units == "auto" {
+		if system, ok := CountryUnits[strings.ToLower(countryCode)]; ok {
+			return system, nil
+		}
+		return "si", nil
+	}
+
+	if _, ok := Formats[units]; !ok {
+		return "", fmt.Errorf("unknown units %q, must be one of: auto, %s", units, strings.Join(slices.Sorted(maps.Keys(Formats)), ", "))
+	}
+
+	return units, nil
+}
+
+// Converter holds one conversion function per kind of measurement.
+type Converter struct {
+	Temperature   func(kelvin float64) float64
+	Speed         func(metersPerSecond float64) float64
+	Length        func(meters float64) float64
+	Pressure      func(hPa float64) float64
+	Precipitation func(mm float64) float64
+}
+
+func KelvinToCelsius(k float64) float64    { return k - 273.15 }
+func KelvinToFahrenheit(k float64) float64 { return (k-273.15)*9/5 + 32 }
+func msToMph(ms float64) float64           { return ms * 2.236936 }
+func msToKmh(ms float64) float64           { return ms * 3.6 }
+func metersToMiles(m float64) float64      { return m / 1609.344 }
+func metersToKm(m float64) float64         { return m / 1000 }
+func hPaToInHg(hPa float64) float64        { return hPa * 0.0295300 }
+func mmToInches(mm float64) float64        { return mm / 25.4 }
+func identity(f float64) float64           { return f }
+
+var Converters = map[string]Converter{
+	"us": {KelvinToFahrenheit, msToMph, metersToMiles, hPaToInHg, mmToInches},
+	"si": {KelvinToCelsius, identity, metersToKm, identity, identity},
+	"ca": {KelvinToCelsius, msToKmh, metersToKm, identity, identity},
+	"uk": {KelvinToCelsius, msToMph, metersToKm, identity, identity},
+}
+
+// Convert returns a copy of forecast with every measurement converted from
+// standard units into the given unit system.
+func Convert(f forecast.Forecast, units string) forecast.Forecast {
+	c, ok := Converters[units]
+	if !ok {
+		return f
+	}
+
+	current := &f.Currently
+	current.Temperature = c.Temperature(current.Temperature)
+	current.FeelsLike = c.Temperature(current.FeelsLike)
+	current.DewPoint = c.Temperature(current.DewPoint)
+	current.WindSpeed = c.Speed(current.WindSpeed)
+	current.Visibility = c.Length(current.Visibility)
+	current.Pressure = c.Pressure(current.Pressure)
+
+	minutely := make([]forecast.MinutelyWeather, len(f.Minutely))
+	for i, minute := range f.Minutely {
+		minute.Precipitation = c.Precipitation(minute.Precipitation)
+		minutely[i] = minute
+	}
+	f.Minutely = minutely
+
+	hourly := make([]forecast.HourlyWeather, len(f.Hourly))
+	for i, hour := range f.Hourly {
+		hour.Temperature = c.Temperature(hour.Temperature)
+		hour.FeelsLike = c.Temperature(hour.FeelsLike)
+		hour.DewPoint = c.Temperature(hour.DewPoint)
+		hour.WindSpeed = c.Speed(hour.WindSpeed)
+		hour.WindGust = c.Speed(hour.WindGust)
+		hour.Visibility = c.Length(hour.Visibility)
+		hour.Pressure = c.Pressure(hour.Pressure)
+		hourly[i] = hour
+	}
+	f.Hourly = hourly
+
+	daily := make([]forecast.DailyWeather, len(f.Daily))
+	for i, day := range f.Daily {
+		temps := []*float64{
+			&day.Temperature.Day, &day.Temperature.Min, &day.Temperature.Max,
+			&day.Temperature.Night, &day.Temperature.Eve, &day.Temperature.Morn,
+			&day.FeelsLike.Day, &day.FeelsLike.Night, &day.FeelsLike.Eve, &day.FeelsLike.Morn,
+			&day.DewPoint,
+		}
+		for _, temp := range temps {
+			*temp = c.Temperature(*temp)
+		}
+		day.WindSpeed = c.Speed(day.WindSpeed)
+		day.WindGust = c.Speed(day.WindGust)
+		day.Pressure = c.Pressure(day.Pressure)
+		day.Rain = c.Precipitation(day.Rain)
+		daily[i] = day
+	}
+	f.Daily = daily
+
+	return f
+}
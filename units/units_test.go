@@ -0,0 +1,61 @@
+package units
+
+import (
+	"math"
+	"testing"
+
+	"github.com/jptoto/weather/forecast"
+)
+
+func TestConvert(t *testing.T) {
+	f := forecast.Forecast{Currently: forecast.CurrentWeather{Temperature: 273.15, WindSpeed: 10, Visibility: 1609.344, Pressure: 1000}}
+
+	tests := []struct {
+		units                            string
+		temp, wind, visibility, pressure float64
+	}{
+		{"us", 32, 22.36936, 1, 29.53},
+		{"si", 0, 10, 1.609344, 1000},
+		{"ca", 0, 36, 1.609344, 1000},
+		{"uk", 0, 22.36936, 1.609344, 1000},
+	}
+
+	for _, test := range tests {
+		t.Run(test.units, func(t *testing.T) {
+			current := Convert(f, test.units).Currently
+			for _, v := range [][2]float64{
+				{current.Temperature, test.temp},
+				{current.WindSpeed, test.wind},
+				{current.Visibility, test.visibility},
+				{current.Pressure, test.pressure},
+			} {
+				if math.Abs(v[0]-v[1]) > 0.001 {
+					t.Errorf("got %v, want %v", v[0], v[1])
+				}
+			}
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		units, country, want string
+	}{
+		{"auto", "us", "us"},
+		{"auto", "GB", "uk"},
+		{"auto", "fr", "si"},
+		{"", "ca", "ca"},
+		{"SI", "us", "si"},
+	}
+
+	for _, test := range tests {
+		got, err := Resolve(test.units, test.country)
+		if err != nil || got != test.want {
+			t.Errorf("Resolve(%q, %q) = %q, %v, want %q", test.units, test.country, got, err, test.want)
+		}
+	}
+
+	if _, err := Resolve("imperial", "us"); err == nil {
+		t.Error("expected an error for unknown units")
+	}
+}
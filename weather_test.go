@@ -0,0 +1,50 @@
+package weather
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jptoto/weather/cache"
+	"github.com/jptoto/weather/geocode"
+	"github.com/jptoto/weather/httpclient"
+)
+
+func TestGazetteerFallbackIsNotCached(t *testing.T) {
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+
+	client := httpclient.Default
+	httpclient.Default = httpclient.New(time.Second, 0)
+	t.Cleanup(func() { httpclient.Default = client })
+
+	c, err := cache.New(t.TempDir(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	services, err := NewServices(Options{
+		GeocoderOptions: geocode.GeocoderOptions{BaseUrl: down.URL, GazetteerDir: filepath.Join("geocode", "testdata", "gazetteer")},
+		Cache:           c,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	locations, err := services.Geocoder.Search(context.Background(), "Paris")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(locations) != 1 || locations[0].Address.Country != "France" {
+		t.Fatalf("got %+v, want the gazetteer's Paris", locations)
+	}
+
+	var cached geocode.GeoLocations
+	if c.Get("geocode", geocode.DefaultGeocoder+"|paris", &cached) {
+		t.Errorf("the gazetteer's answer was cached as %s's: %+v", geocode.DefaultGeocoder, cached)
+	}
+}